@@ -9,25 +9,43 @@ import (
 
 var biggest int
 
-func parseMaps(lines []string) []util.RangeMap {
-	maps := make([]util.RangeMap, 0)
-	var newMap *util.RangeMap
+type header struct {
+	source, destination string
+}
+
+func parsePipeline(lines []string) (util.Pipeline, error) {
+	pipeline := util.Pipeline{}
+	headers := make([]header, 0)
+	builders := make([]util.Builder[int, int], 0)
 	for _, line := range lines {
 		if line == "" {
 			continue
 		}
-		if strings.HasSuffix(line, ":") { // new map:
-			maps = append(maps, util.RangeMap{})
-			newMap = &maps[len(maps)-1]
+		if name, ok := strings.CutSuffix(line, " map:"); ok { // new map:
+			source, destination, found := strings.Cut(name, "-to-")
+			if !found {
+				return pipeline, fmt.Errorf("invalid map header %q", line)
+			}
+			headers = append(headers, header{source, destination})
+			builders = append(builders, util.Builder[int, int]{})
 			continue
 		}
 		//
 		rangeNums := util.ParseNumberList(line)
 		delta := rangeNums[0] - rangeNums[1]
-		sourceSpan := util.Span{rangeNums[1], rangeNums[1] + rangeNums[2]}
-		newMap.Add(sourceSpan, delta)
+		sourceSpan := util.SpanLen(rangeNums[1], rangeNums[2])
+		builders[len(builders)-1].Add(sourceSpan, delta)
+	}
+	for index := range builders {
+		set, err := builders[index].Build(util.OverlapReject, nil)
+		if err != nil {
+			return pipeline, fmt.Errorf("%s-to-%s map: %w", headers[index].source, headers[index].destination, err)
+		}
+		if err := pipeline.Add(headers[index].source, headers[index].destination, util.RangeMap(set)); err != nil {
+			return pipeline, err
+		}
 	}
-	return maps
+	return pipeline, nil
 }
 
 func mapMinValue(seeds []int, seedMap util.RangeMap) int {
@@ -41,20 +59,17 @@ func mapMinValue(seeds []int, seedMap util.RangeMap) int {
 	return minValue
 }
 
-func mapMinRange(seeds []int, seedMap util.RangeMap) int {
-	seedSet := util.RangeMap{}
+func mapMinRange(seeds []int, pipeline util.Pipeline) int {
+	seedSet := util.SpanSet{}
 	for index := 0; index < len(seeds); index += 2 {
-		seedSet.Add(util.Span{seeds[index], seeds[index] + seeds[index+1]}, 0)
+		seedSet.Add(util.SpanLen(seeds[index], seeds[index+1]), struct{}{})
 	}
-	seedMap = seedSet.CombineMap(seedMap, false)
-	minValue := util.RangeSet[int](seedMap).Min() + *util.RangeSet[int](seedMap).MinValue()
-	util.RangeSet[int](seedMap).Do(func(s util.Span, delta *int) bool {
-		if value := s[0] + *delta; value < minValue {
-			minValue = value
-		}
-		return true
-	})
-	return minValue
+	maps := make([]util.RangeMap, 0)
+	for _, stage := range pipeline.Stages() {
+		maps = append(maps, stage.Map)
+	}
+	location, _, _ := util.MinImage(seedSet, maps...)
+	return location
 }
 
 func main() {
@@ -66,11 +81,17 @@ func main() {
 	_, seedLine, _ := strings.Cut(lines[0], ": ")
 	seeds := util.ParseNumberList(seedLine)
 	fmt.Printf("max %d\n", util.FindMax(seeds))
-	maps := parseMaps(lines[2:])
+	pipeline, err := parsePipeline(lines[2:])
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
 
-	seedMap := maps[0].Reduce(maps[1:])
+	seedMap, err := pipeline.Compose("seed", "location")
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
 	fmt.Println(mapMinValue(seeds, seedMap))
-	fmt.Println(mapMinRange(seeds, seedMap))
+	fmt.Println(mapMinRange(seeds, pipeline))
 }
 
 // vim: set ts=2 sw=2:
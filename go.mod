@@ -1,3 +1,3 @@
 module advent2023
 
-go 1.21.4
+go 1.24
@@ -0,0 +1,264 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Affine is the transform x -> Scale*x + Offset.
+type AffineOf[K Signed] struct {
+	Scale  K
+	Offset K
+}
+
+type Affine = AffineOf[int]
+
+func (a AffineOf[K]) Apply(x K) K {
+	return a.Scale*x + a.Offset
+}
+
+// Then returns the transform which applies a and then b.
+func (a AffineOf[K]) Then(b AffineOf[K]) AffineOf[K] {
+	return AffineOf[K]{b.Scale * a.Scale, b.Scale*a.Offset + b.Offset}
+}
+
+func (a AffineOf[K]) String() string {
+	return fmt.Sprintf("%d*x%+d", a.Scale, a.Offset)
+}
+
+func floorDiv[K Signed](x, y K) K {
+	q := x / y
+	if x%y != 0 && (x < 0) != (y < 0) {
+		q--
+	}
+	return q
+}
+
+func ceilDiv[K Signed](x, y K) K {
+	return -floorDiv(-x, y)
+}
+
+// preimage returns the points in domain which the transform maps into span.
+func (a AffineOf[K]) preimage(span SpanOf[K], domain SpanOf[K]) SpanOf[K] {
+	var result SpanOf[K]
+	switch {
+	case a.Scale > 0:
+		result = SpanOf[K]{ceilDiv(span[0]-a.Offset, a.Scale), ceilDiv(span[1]-a.Offset, a.Scale)}
+	case a.Scale < 0:
+		result = SpanOf[K]{floorDiv(span[1]-a.Offset, a.Scale) + 1, floorDiv(span[0]-a.Offset, a.Scale) + 1}
+	case span.Contains(a.Offset):
+		result = domain
+	default:
+		return SpanOf[K]{domain[0], domain[0]}
+	}
+	return domain.Intersect(result)
+}
+
+var ErrNotContiguous = errors.New("image is not contiguous")
+
+// image returns the image of a non-empty span under the transform.
+func (a AffineOf[K]) image(span SpanOf[K]) (SpanOf[K], error) {
+	switch {
+	case a.Scale == 0 || span[1]-span[0] == 1:
+		return SpanOf[K]{a.Apply(span[0]), a.Apply(span[0]) + 1}, nil
+	case a.Scale == 1:
+		return span.Shift(a.Offset), nil
+	case a.Scale == -1:
+		return SpanOf[K]{a.Offset - span[1] + 1, a.Offset - span[0] + 1}, nil
+	}
+	return SpanOf[K]{}, fmt.Errorf("%w: %s under %s", ErrNotContiguous, span, a)
+}
+
+// AffineRangeMap maps each span through its own affine transform.
+//
+// Unmapped points map to themselves.
+type AffineRangeMapOf[K Signed] RangeSetOf[K, AffineOf[K]]
+type AffineRangeMap = AffineRangeMapOf[int]
+
+func (s *AffineRangeMapOf[K]) Add(span SpanOf[K], value AffineOf[K]) {
+	s.set.add(span, value)
+}
+
+// AddWith inserts a span into the map, resolving overlaps by policy.
+func (s *AffineRangeMapOf[K]) AddWith(span SpanOf[K], value AffineOf[K], policy OverlapPolicy, combine CombineFunc[AffineOf[K]]) error {
+	return s.set.addWith(span, value, policy, combine)
+}
+
+// AffineMap returns the map as an affine map of translations.
+func AffineMap[K Signed](s RangeMapOf[K]) AffineRangeMapOf[K] {
+	result := AffineRangeMapOf[K]{}
+	for info := range s.set.from(0) {
+		result.set.extend(info.span, AffineOf[K]{1, info.value})
+	}
+	result.set.check()
+	return result
+}
+
+// compose appends the composition of a transform over a domain with the map.
+//
+// It sweeps the span from the least to the greatest image of the domain
+// against the map. The preimages of the segments tile the domain, so points
+// whose image the map leaves alone keep the transform.
+func (s AffineRangeMapOf[K]) compose(domain SpanOf[K], transform AffineOf[K], result *[]spanValue[K, AffineOf[K]]) {
+	if domain.Empty() {
+		return
+	}
+	lo := transform.Apply(domain[0])
+	hi := transform.Apply(domain[1] - 1)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	image := RangeSetOf[K, AffineOf[K]]{}
+	image.set.items = append(image.set.items, spanValue[K, AffineOf[K]]{SpanOf[K]{lo, hi + 1}, transform})
+	pieces := make([]spanValue[K, AffineOf[K]], 0)
+	sweepPair(image, RangeSetOf[K, AffineOf[K]](s), lo, func(part SpanOf[K], sInfo, tInfo *spanValue[K, AffineOf[K]]) bool {
+		if sInfo == nil {
+			return false
+		}
+		value := transform
+		if tInfo != nil {
+			value = transform.Then(tInfo.value)
+		}
+		if pre := transform.preimage(part, domain); !pre.Empty() {
+			pieces = append(pieces, spanValue[K, AffineOf[K]]{pre, value})
+		}
+		return true
+	})
+	if transform.Scale < 0 {
+		slices.Reverse(pieces)
+	}
+	for _, piece := range pieces {
+		extend(result, piece.span, piece.value)
+	}
+}
+
+// CombineMap returns the map which applies s and then t.
+//
+// If mergeUnmapped is false, the result only maps the domain of s.
+func (s AffineRangeMapOf[K]) CombineMap(t AffineRangeMapOf[K], mergeUnmapped bool) AffineRangeMapOf[K] {
+	if t.set.len() == 0 {
+		return s
+	}
+	if s.set.len() == 0 {
+		if mergeUnmapped {
+			return t
+		}
+		return AffineRangeMapOf[K]{}
+	}
+	// Pull t back through s, then outside the domain of s only t applies.
+	pullback := RangeSetOf[K, AffineOf[K]]{}
+	for info := range s.set.from(0) {
+		t.compose(info.span, info.value, &pullback.set.items)
+	}
+	if mergeUnmapped {
+		pullback = pullback.Union(RangeSetOf[K, AffineOf[K]](t))
+	}
+	result := AffineRangeMapOf[K](pullback)
+	result.set.check()
+	return result
+}
+
+func (s AffineRangeMapOf[K]) Reduce(maps []AffineRangeMapOf[K]) AffineRangeMapOf[K] {
+	return s.ReduceWith(maps, false)
+}
+
+// ReduceWith combines a chain of maps, optionally normalizing the result
+// after each step to keep the intermediate maps small.
+func (s AffineRangeMapOf[K]) ReduceWith(maps []AffineRangeMapOf[K], normalize bool) AffineRangeMapOf[K] {
+	result := s
+	for _, rangeMap := range maps {
+		result = result.CombineMap(rangeMap, true)
+		if normalize {
+			result = result.Normalize()
+		}
+	}
+	return result
+}
+
+// Normalize returns the map with adjacent ranges of equal transform coalesced.
+func (s AffineRangeMapOf[K]) Normalize() AffineRangeMapOf[K] {
+	return AffineRangeMapOf[K](Coalesce(RangeSetOf[K, AffineOf[K]](s)))
+}
+
+func (s AffineRangeMapOf[K]) Map(value K) K {
+	if transform := s.set.get(value); transform != nil {
+		return transform.Apply(value)
+	}
+	return value
+}
+
+func (s AffineRangeMapOf[K]) Maps(value K) bool {
+	return s.set.get(value) != nil
+}
+
+// MapSpan returns the image of a span through the map.
+//
+// Unmapped parts of the span map to themselves. An error is returned if the
+// image of any part is not contiguous, as when it is scaled by more than one.
+func (s AffineRangeMapOf[K]) MapSpan(span SpanOf[K]) (SpanSetOf[K], error) {
+	set := SpanSetOf[K]{}
+	if !span.Empty() {
+		set.Add(span, struct{}{})
+	}
+	return s.MapSet(set)
+}
+
+// MapSet returns the image of a set of spans through the map.
+//
+// See MapSpan.
+func (s AffineRangeMapOf[K]) MapSet(set SpanSetOf[K]) (SpanSetOf[K], error) {
+	pieces := make([]spanValue[K, AffineOf[K]], 0, set.set.len())
+	for info := range set.set.from(0) {
+		s.compose(info.span, AffineOf[K]{1, 0}, &pieces)
+	}
+	images := make([]SpanOf[K], 0, len(pieces))
+	for _, piece := range pieces {
+		image, err := piece.value.image(piece.span)
+		if err != nil {
+			return SpanSetOf[K]{}, err
+		}
+		images = append(images, image)
+	}
+	return coalesce(images), nil
+}
+
+// Invert returns the inverse of the map.
+//
+// The map is invertible only if it is a bijection; in particular every
+// range longer than one point must be a translation or a reflection.
+func (s AffineRangeMapOf[K]) Invert() (AffineRangeMapOf[K], error) {
+	inverse := AffineRangeMapOf[K]{}
+	for info := range s.set.from(0) {
+		image, err := info.value.image(info.span)
+		if err != nil {
+			return AffineRangeMapOf[K]{}, fmt.Errorf("%w: %w", ErrNotInvertible, err)
+		}
+		transform := AffineOf[K]{1, info.span[0] - image[0]}
+		if info.value.Scale == -1 {
+			transform = info.value
+		} else if info.value.Scale == 0 && image[1]-image[0] < info.span[1]-info.span[0] {
+			return AffineRangeMapOf[K]{}, fmt.Errorf("%w: %s is not injective on %s", ErrNotInvertible, info.value, info.span)
+		}
+		if err := inverse.AddWith(image, transform, OverlapReject, nil); err != nil {
+			return AffineRangeMapOf[K]{}, fmt.Errorf("%w: %w", ErrNotInvertible, err)
+		}
+	}
+	var collision *SpanOf[K]
+	RangeSetOf[K, AffineOf[K]](inverse).DoDifference(RangeSetOf[K, AffineOf[K]](s), func(span SpanOf[K], _ *AffineOf[K]) bool {
+		collision = &span
+		return false
+	})
+	if collision != nil {
+		return AffineRangeMapOf[K]{}, fmt.Errorf("%w: %s is the image of mapped and unmapped points", ErrNotInvertible, *collision)
+	}
+	return inverse, nil
+}
+
+func (s AffineRangeMapOf[K]) String() string {
+	return RangeSetOf[K, AffineOf[K]](s).String()
+}
+
+func (s AffineRangeMapOf[K]) Count() int {
+	return s.set.len()
+}
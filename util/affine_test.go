@@ -0,0 +1,98 @@
+package util
+
+import (
+	"errors"
+	"testing"
+)
+
+func makeAffineMap(entries ...spanValue[int, Affine]) AffineRangeMap {
+	result := AffineRangeMap{}
+	for _, entry := range entries {
+		result.Add(entry.span, entry.value)
+	}
+	return result
+}
+
+func TestAffineCombineMap(t *testing.T) {
+	maps := []AffineRangeMap{
+		makeAffineMap(spanValue[int, Affine]{Span{0, 10}, Affine{2, 3}}, spanValue[int, Affine]{Span{10, 20}, Affine{-1, 40}}),
+		makeAffineMap(spanValue[int, Affine]{Span{5, 12}, Affine{1, -5}}, spanValue[int, Affine]{Span{20, 30}, Affine{-3, 7}}),
+		makeAffineMap(spanValue[int, Affine]{Span{-60, -20}, Affine{0, 1}}, spanValue[int, Affine]{Span{25, 27}, Affine{5, 0}}),
+		AffineMap(RangeMap(makeRangeSet(spanValue[int, int]{Span{-5, 5}, 50}, spanValue[int, int]{Span{100, 200}, -100}))),
+	}
+	for _, s := range maps {
+		for _, u := range maps {
+			for _, mergeUnmapped := range []bool{false, true} {
+				combined := s.CombineMap(u, mergeUnmapped)
+				for value := -70; value < 70; value++ {
+					expected := value
+					if s.Maps(value) || (mergeUnmapped && u.Maps(value)) {
+						expected = u.Map(s.Map(value))
+					}
+					if got := combined.Map(value); got != expected {
+						t.Errorf("%s.CombineMap(%s, %t).Map(%d): expected %d, got %d",
+							s, u, mergeUnmapped, value, expected, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAffineMapSpan(t *testing.T) {
+	rangeMap := makeAffineMap(
+		spanValue[int, Affine]{Span{0, 5}, Affine{-1, 20}},
+		spanValue[int, Affine]{Span{5, 10}, Affine{1, 100}},
+		spanValue[int, Affine]{Span{10, 20}, Affine{0, -7}},
+		spanValue[int, Affine]{Span{20, 30}, Affine{2, 0}},
+	)
+	image, err := rangeMap.MapSpan(Span{-2, 20})
+	if err != nil {
+		t.Fatalf("MapSpan: unexpected error %v", err)
+	}
+	for value := -2; value < 20; value++ {
+		if image.Get(rangeMap.Map(value)) == nil {
+			t.Errorf("MapSpan: image %s does not contain %d", image, rangeMap.Map(value))
+		}
+	}
+	measure := 0
+	image.Do(func(span Span, _ *struct{}) bool {
+		measure += span[1] - span[0]
+		return true
+	})
+	if expected := 5 + 5 + 1 + 2; measure != expected {
+		t.Errorf("MapSpan: expected measure %d, got image %s", expected, image)
+	}
+	if _, err := rangeMap.MapSpan(Span{20, 22}); !errors.Is(err, ErrNotContiguous) {
+		t.Errorf("MapSpan: expected error %v, got %v", ErrNotContiguous, err)
+	}
+}
+
+func TestAffineInvert(t *testing.T) {
+	type test struct {
+		rangeMap AffineRangeMap
+		err      error
+	}
+	for _, test := range []test{
+		{makeAffineMap(spanValue[int, Affine]{Span{0, 10}, Affine{-1, 9}}), nil},
+		{makeAffineMap(spanValue[int, Affine]{Span{0, 10}, Affine{-1, 19}}, spanValue[int, Affine]{Span{10, 20}, Affine{1, -10}}), nil},
+		{makeAffineMap(spanValue[int, Affine]{Span{0, 1}, Affine{3, 1}}, spanValue[int, Affine]{Span{1, 2}, Affine{1, -1}}), nil},
+		{makeAffineMap(spanValue[int, Affine]{Span{0, 10}, Affine{2, 0}}), ErrNotInvertible},
+		{makeAffineMap(spanValue[int, Affine]{Span{0, 10}, Affine{0, 0}}), ErrNotInvertible},
+		{makeAffineMap(spanValue[int, Affine]{Span{0, 10}, Affine{-1, 10}}), ErrNotInvertible},
+	} {
+		inverse, err := test.rangeMap.Invert()
+		if !errors.Is(err, test.err) {
+			t.Errorf("Invert(%s): expected error %v, got %v", test.rangeMap, test.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		for value := -5; value < 25; value++ {
+			if mapped := test.rangeMap.Map(value); inverse.Map(mapped) != value {
+				t.Errorf("Invert(%s): %d maps to %d but inverse maps it to %d",
+					test.rangeMap, value, mapped, inverse.Map(mapped))
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package util
+
+import (
+	"strings"
+)
+
+// BoxOf is an axis-aligned box: the product of one half-open span per axis.
+type BoxOf[K Integer] []SpanOf[K]
+type Box = BoxOf[int]
+
+func Box2[K Integer](x, y SpanOf[K]) BoxOf[K] {
+	return BoxOf[K]{x, y}
+}
+
+func Box3[K Integer](x, y, z SpanOf[K]) BoxOf[K] {
+	return BoxOf[K]{x, y, z}
+}
+
+func (b BoxOf[K]) checkDim(c BoxOf[K]) {
+	if len(b) != len(c) {
+		panic("box dimensions differ")
+	}
+}
+
+// Empty reports whether the box contains no points.
+func (b BoxOf[K]) Empty() bool {
+	for _, span := range b {
+		if span.Empty() {
+			return true
+		}
+	}
+	return false
+}
+
+func (b BoxOf[K]) Contains(point ...K) bool {
+	if len(point) != len(b) {
+		return false
+	}
+	for axis, span := range b {
+		if !span.Contains(point[axis]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b BoxOf[K]) Overlaps(c BoxOf[K]) bool {
+	b.checkDim(c)
+	for axis, span := range b {
+		if !span.Overlaps(c[axis]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b BoxOf[K]) Intersect(c BoxOf[K]) BoxOf[K] {
+	b.checkDim(c)
+	result := make(BoxOf[K], len(b))
+	for axis, span := range b {
+		result[axis] = span.Intersect(c[axis])
+	}
+	return result
+}
+
+// Subtract returns disjoint boxes covering the points of b which are not in c.
+//
+// Along each axis in turn the slabs of b below and above c are split off,
+// and the remainder is clipped to c on that axis.
+func (b BoxOf[K]) Subtract(c BoxOf[K]) []BoxOf[K] {
+	if b.Empty() {
+		return nil
+	}
+	if !b.Overlaps(c) {
+		return []BoxOf[K]{b}
+	}
+	result := make([]BoxOf[K], 0, 2*len(b))
+	rest := append(BoxOf[K]{}, b...)
+	for axis, span := range c {
+		if rest[axis][0] < span[0] {
+			slab := append(BoxOf[K]{}, rest...)
+			slab[axis] = SpanOf[K]{rest[axis][0], span[0]}
+			result = append(result, slab)
+		}
+		if span[1] < rest[axis][1] {
+			slab := append(BoxOf[K]{}, rest...)
+			slab[axis] = SpanOf[K]{span[1], rest[axis][1]}
+			result = append(result, slab)
+		}
+		rest[axis] = rest[axis].Intersect(span)
+	}
+	return result
+}
+
+// Volume returns the number of points in the box.
+func (b BoxOf[K]) Volume() K {
+	if b.Empty() {
+		return 0
+	}
+	volume := K(1)
+	for _, span := range b {
+		volume *= span.Len()
+	}
+	return volume
+}
+
+func (b BoxOf[K]) String() string {
+	axes := make([]string, len(b))
+	for axis, span := range b {
+		axes[axis] = span.String()
+	}
+	return strings.Join(axes, "x")
+}
+
+// BoxSetOf is a union of boxes, stored as disjoint boxes.
+type BoxSetOf[K Integer] struct {
+	boxes []BoxOf[K]
+}
+
+type BoxSet = BoxSetOf[int]
+
+// Add adds the points of a box to the set.
+func (s *BoxSetOf[K]) Add(b BoxOf[K]) {
+	pieces := []BoxOf[K]{b}
+	for _, box := range s.boxes {
+		remaining := make([]BoxOf[K], 0, len(pieces))
+		for _, piece := range pieces {
+			remaining = append(remaining, piece.Subtract(box)...)
+		}
+		pieces = remaining
+	}
+	for _, piece := range pieces {
+		if !piece.Empty() {
+			s.boxes = append(s.boxes, piece)
+		}
+	}
+}
+
+// Remove removes the points of a box from the set.
+func (s *BoxSetOf[K]) Remove(b BoxOf[K]) {
+	boxes := make([]BoxOf[K], 0, len(s.boxes))
+	for _, box := range s.boxes {
+		boxes = append(boxes, box.Subtract(b)...)
+	}
+	s.boxes = boxes
+}
+
+func (s BoxSetOf[K]) Contains(point ...K) bool {
+	for _, box := range s.boxes {
+		if box.Contains(point...) {
+			return true
+		}
+	}
+	return false
+}
+
+// Volume returns the number of points in the set.
+func (s BoxSetOf[K]) Volume() K {
+	volume := K(0)
+	for _, box := range s.boxes {
+		volume += box.Volume()
+	}
+	return volume
+}
+
+// Do invokes a function on each of the disjoint boxes in the set.
+//
+// If a call returns false, no more boxes are visited.
+func (s BoxSetOf[K]) Do(do func(BoxOf[K]) bool) {
+	for _, box := range s.boxes {
+		if !do(box) {
+			break
+		}
+	}
+}
+
+func (s BoxSetOf[K]) Count() int {
+	return len(s.boxes)
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestBoxSubtract(t *testing.T) {
+	type test struct {
+		box, sub BoxOf[int]
+	}
+	for _, test := range []test{
+		{Box2(Span{0, 5}, Span{0, 5}), Box2(Span{1, 3}, Span{2, 4})},
+		{Box2(Span{0, 5}, Span{0, 5}), Box2(Span{-1, 3}, Span{2, 8})},
+		{Box2(Span{0, 5}, Span{0, 5}), Box2(Span{5, 7}, Span{0, 5})},
+		{Box2(Span{0, 5}, Span{0, 5}), Box2(Span{-5, 10}, Span{-5, 10})},
+		{Box3(Span{0, 4}, Span{0, 4}, Span{0, 4}), Box3(Span{1, 2}, Span{1, 2}, Span{1, 2})},
+	} {
+		pieces := test.box.Subtract(test.sub)
+		volume := 0
+		for _, piece := range pieces {
+			volume += piece.Volume()
+		}
+		if expected := test.box.Volume() - test.box.Intersect(test.sub).Volume(); volume != expected {
+			t.Errorf("%s.Subtract(%s): expected volume %d, got %d from %v", test.box, test.sub, expected, volume, pieces)
+		}
+		for index, piece := range pieces {
+			if piece.Overlaps(test.sub) {
+				t.Errorf("%s.Subtract(%s): %s overlaps subtrahend", test.box, test.sub, piece)
+			}
+			for _, other := range pieces[index+1:] {
+				if piece.Overlaps(other) {
+					t.Errorf("%s.Subtract(%s): %s overlaps %s", test.box, test.sub, piece, other)
+				}
+			}
+		}
+	}
+}
+
+func TestBoxSet(t *testing.T) {
+	type step struct {
+		on  bool
+		box BoxOf[int]
+	}
+	steps := []step{
+		{true, Box3(Span{10, 13}, Span{10, 13}, Span{10, 13})},
+		{true, Box3(Span{11, 14}, Span{11, 14}, Span{11, 14})},
+		{false, Box3(Span{9, 12}, Span{9, 12}, Span{9, 12})},
+		{true, Box3(Span{10, 11}, Span{10, 11}, Span{10, 11})},
+	}
+	set := BoxSet{}
+	for _, step := range steps {
+		if step.on {
+			set.Add(step.box)
+		} else {
+			set.Remove(step.box)
+		}
+	}
+	if volume := set.Volume(); volume != 39 {
+		t.Errorf("Volume: expected %d, got %d", 39, volume)
+	}
+	for x := 8; x < 16; x++ {
+		for y := 8; y < 16; y++ {
+			for z := 8; z < 16; z++ {
+				expected := false
+				for _, step := range steps {
+					if step.box.Contains(x, y, z) {
+						expected = step.on
+					}
+				}
+				if set.Contains(x, y, z) != expected {
+					t.Errorf("Contains(%d,%d,%d): expected %t", x, y, z, expected)
+				}
+			}
+		}
+	}
+}
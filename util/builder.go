@@ -0,0 +1,75 @@
+package util
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Entry is a span and its value, as accepted by FromSpans.
+type Entry[K Integer, T any] struct {
+	Span  SpanOf[K]
+	Value T
+}
+
+// FromSpans builds a set from entries in any order.
+//
+// The result is the same as adding each entry in turn with AddWith, but the
+// entries are sorted once: without overlaps this costs O(n log n). Overlaps
+// are rejected with ErrOverlap under OverlapReject; under the other policies
+// they are resolved in the order of entries, in amortized O(n log n) for
+// OverlapOverwrite. Empty spans are ignored.
+func FromSpans[K Integer, T any](entries []Entry[K, T], policy OverlapPolicy, combine CombineFunc[T]) (RangeSetOf[K, T], error) {
+	sorted := make([]spanValue[K, T], 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Span.Empty() {
+			sorted = append(sorted, spanValue[K, T]{entry.Span, entry.Value})
+		}
+	}
+	slices.SortStableFunc(sorted, func(x, y spanValue[K, T]) int {
+		return cmp.Compare(x.span[0], y.span[0])
+	})
+	// Ends increase while there are no overlaps, so it is enough to check
+	// each span against the one before it.
+	for index := 1; index < len(sorted); index++ {
+		if sorted[index].span[0] < sorted[index-1].span[1] {
+			if policy == OverlapReject {
+				return RangeSetOf[K, T]{}, fmt.Errorf("%w: %s overlaps %s", ErrOverlap, sorted[index].span, sorted[index-1].span)
+			}
+			return fromOverlapping(entries, policy, combine)
+		}
+	}
+	result := RangeSetOf[K, T]{set: spanStore[K, T]{items: sorted}}
+	result.set.check()
+	return result, nil
+}
+
+// fromOverlapping adds entries in order to a tree-backed store, so that
+// each overlap costs O(log n) however the entries fall.
+func fromOverlapping[K Integer, T any](entries []Entry[K, T], policy OverlapPolicy, combine CombineFunc[T]) (RangeSetOf[K, T], error) {
+	store := newSpanStore[K, T](TreeBackend)
+	for _, entry := range entries {
+		if err := store.addWith(entry.Span, entry.Value, policy, combine); err != nil {
+			return RangeSetOf[K, T]{}, err
+		}
+	}
+	return RangeSetOf[K, T]{set: spanStore[K, T]{items: store.slice()}}, nil
+}
+
+// Builder collects entries to build a set with FromSpans.
+type Builder[K Integer, T any] struct {
+	entries []Entry[K, T]
+}
+
+func (b *Builder[K, T]) Add(span SpanOf[K], value T) {
+	b.entries = append(b.entries, Entry[K, T]{span, value})
+}
+
+func (b *Builder[K, T]) Len() int {
+	return len(b.entries)
+}
+
+// Build returns the set of the entries added so far.
+func (b *Builder[K, T]) Build(policy OverlapPolicy, combine CombineFunc[T]) (RangeSetOf[K, T], error) {
+	return FromSpans(b.entries, policy, combine)
+}
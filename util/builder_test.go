@@ -0,0 +1,47 @@
+package util
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestFromSpans(t *testing.T) {
+	random := rand.New(rand.NewPCG(3, 4))
+	concat := func(x, y *string) string {
+		return *x + *y
+	}
+	for _, policy := range []OverlapPolicy{OverlapOverwrite, OverlapMerge} {
+		for trial := 0; trial < 50; trial++ {
+			builder := Builder[int, string]{}
+			expected := RangeSet[string]{}
+			for range random.IntN(40) {
+				lo := random.IntN(100)
+				span := Span{lo, lo + random.IntN(15)}
+				value := string(rune('a' + random.IntN(26)))
+				builder.Add(span, value)
+				expected.AddWith(span, value, policy, concat)
+			}
+			set, err := builder.Build(policy, concat)
+			if err != nil {
+				t.Fatalf("Build(%d): unexpected error %v", policy, err)
+			}
+			if set.String() != expected.String() {
+				t.Errorf("Build(%d): expected %s, got %s", policy, expected, set)
+			}
+		}
+	}
+
+	entries := make([]Entry[int, int], 0)
+	for index, span := range randomSpans(1000, 5) {
+		entries = append(entries, Entry[int, int]{span, index})
+	}
+	set, err := FromSpans(entries, OverlapReject, nil)
+	if err != nil || set.set.len() != len(entries) || set.Validate() != nil {
+		t.Errorf("FromSpans: expected %d ranges, got %d, %v", len(entries), set.set.len(), err)
+	}
+	entries = append(entries, Entry[int, int]{Span{10, 20}, 0})
+	if _, err := FromSpans(entries, OverlapReject, nil); !errors.Is(err, ErrOverlap) {
+		t.Errorf("FromSpans: expected ErrOverlap, got %v", err)
+	}
+}
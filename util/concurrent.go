@@ -0,0 +1,148 @@
+package util
+
+import (
+	"sync"
+)
+
+// ConcurrentRangeSetOf is a RangeSet which is safe for concurrent use.
+//
+// Queries take a read lock and return copies of values rather than pointers
+// into the set, so results stay valid while other goroutines write. Values
+// are copied shallowly. The zero value is an empty set using SliceBackend.
+type ConcurrentRangeSetOf[K Integer, T any] struct {
+	mutex sync.RWMutex
+	set   RangeSetOf[K, T]
+}
+type ConcurrentRangeSet[T any] = ConcurrentRangeSetOf[int, T]
+
+// NewConcurrentRangeSet returns an empty set using the given backend.
+//
+// TreeBackend suits sets which are written often.
+func NewConcurrentRangeSet[K Integer, T any](backend Backend) *ConcurrentRangeSetOf[K, T] {
+	return &ConcurrentRangeSetOf[K, T]{set: NewRangeSet[K, T](backend)}
+}
+
+// Add inserts a span into the set without checking for overlaps.
+func (c *ConcurrentRangeSetOf[K, T]) Add(span SpanOf[K], value T) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.set.Add(span, value)
+}
+
+// AddWith inserts a span into the set, resolving overlaps by policy.
+func (c *ConcurrentRangeSetOf[K, T]) AddWith(span SpanOf[K], value T, policy OverlapPolicy, combine CombineFunc[T]) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.set.AddWith(span, value, policy, combine)
+}
+
+func (c *ConcurrentRangeSetOf[K, T]) Remove(span SpanOf[K]) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.set.Remove(span)
+}
+
+// Update calls a function with the set under the write lock, so that several
+// changes apply atomically. The function must not keep the set.
+func (c *ConcurrentRangeSetOf[K, T]) Update(update func(*RangeSetOf[K, T])) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	update(&c.set)
+}
+
+// entry returns a copy of a range, or false if there is none.
+func entry[K Integer, T any](result *RangeResultOf[K, T]) (Entry[K, T], bool) {
+	if result == nil {
+		return Entry[K, T]{}, false
+	}
+	return Entry[K, T]{result.Span, *result.Value}, true
+}
+
+// Get returns the value of the range containing key.
+func (c *ConcurrentRangeSetOf[K, T]) Get(key K) (T, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	result, ok := entry(c.set.GetRange(key))
+	return result.Value, ok
+}
+
+// GetRange returns the range containing key.
+func (c *ConcurrentRangeSetOf[K, T]) GetRange(key K) (Entry[K, T], bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return entry(c.set.GetRange(key))
+}
+
+// Floor returns the range containing key or the last range before it.
+func (c *ConcurrentRangeSetOf[K, T]) Floor(key K) (Entry[K, T], bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return entry(c.set.Floor(key))
+}
+
+// Ceiling returns the range containing key or the first range after it.
+func (c *ConcurrentRangeSetOf[K, T]) Ceiling(key K) (Entry[K, T], bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return entry(c.set.Ceiling(key))
+}
+
+// Overlapping returns the ranges which overlap a span, in order.
+//
+// If clip is true, the returned spans are clipped to the span.
+func (c *ConcurrentRangeSetOf[K, T]) Overlapping(span SpanOf[K], clip bool) []Entry[K, T] {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	results := c.set.Overlapping(span, clip)
+	entries := make([]Entry[K, T], len(results))
+	for index, result := range results {
+		entries[index], _ = entry(&result)
+	}
+	return entries
+}
+
+func (c *ConcurrentRangeSetOf[K, T]) Measure() K {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.set.Measure()
+}
+
+func (c *ConcurrentRangeSetOf[K, T]) Span() SpanOf[K] {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.set.Span()
+}
+
+func (c *ConcurrentRangeSetOf[K, T]) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.set.set.len()
+}
+
+// Do invokes a function on a copy of each range in the set under the read
+// lock, so the function must not write to the set.
+//
+// If a call returns false, no more ranges are visited.
+func (c *ConcurrentRangeSetOf[K, T]) Do(do func(SpanOf[K], T) bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for info := range c.set.set.from(0) {
+		if !do(info.span, info.value) {
+			return
+		}
+	}
+}
+
+// Snapshot returns a copy of the set which the caller owns, for queries and
+// operations such as Cover which the wrapper does not provide.
+func (c *ConcurrentRangeSetOf[K, T]) Snapshot() RangeSetOf[K, T] {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.set.Clone()
+}
+
+func (c *ConcurrentRangeSetOf[K, T]) String() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.set.String()
+}
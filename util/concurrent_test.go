@@ -0,0 +1,57 @@
+package util
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestConcurrentRangeSet is most useful under the race detector:
+//
+//	go test -race ./util
+func TestConcurrentRangeSet(t *testing.T) {
+	for _, backend := range []Backend{SliceBackend, TreeBackend} {
+		set := NewConcurrentRangeSet[int, int](backend)
+		const workers, spans = 8, 200
+		var wait sync.WaitGroup
+		for worker := range workers {
+			wait.Add(2)
+			go func() {
+				defer wait.Done()
+				for index := range spans {
+					lo := 10 * (index*workers + worker)
+					if err := set.AddWith(Span{lo, lo + 5}, worker, OverlapReject, nil); err != nil {
+						t.Errorf("AddWith: unexpected error %v", err)
+					}
+				}
+			}()
+			go func() {
+				defer wait.Done()
+				for index := range spans {
+					key := 10*(index*workers+worker) + 2
+					if value, ok := set.Get(key); ok && value != worker {
+						t.Errorf("Get(%d): expected %d, got %d", key, worker, value)
+					}
+					set.Overlapping(Span{key - 20, key + 20}, true)
+					set.Floor(key)
+					set.Measure()
+				}
+			}()
+		}
+		wait.Wait()
+
+		if length := set.Len(); length != workers*spans {
+			t.Errorf("Len: expected %d, got %d", workers*spans, length)
+		}
+		snapshot := set.Snapshot()
+		set.Update(func(s *RangeSet[int]) {
+			s.Remove(Span{0, 1000})
+			s.Add(Span{0, 1000}, -1)
+		})
+		if err := snapshot.Validate(); err != nil || snapshot.set.len() != workers*spans || *snapshot.Get(2) != 0 {
+			t.Errorf("Snapshot: changed by Update: %v", err)
+		}
+		if value, ok := set.Get(2); !ok || value != -1 || set.Measure() != 1000+5*(workers*spans-100) {
+			t.Errorf("Update: expected [0,1000) = -1, got %d, %t", value, ok)
+		}
+	}
+}
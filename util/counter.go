@@ -0,0 +1,107 @@
+package util
+
+// SpanCounterOf counts how many spans cover each point.
+//
+// Spans may overlap. The counter keeps the depth function as a set of ranges
+// of constant non-zero depth, which each Add or Remove updates with a cover
+// sweep in O(n). AddAll and RemoveAll update it for many spans at once in
+// O(n log n). Each update builds new storage, so copies of a counter are
+// independent, and reads do not modify the counter.
+type SpanCounterOf[K Integer] struct {
+	depths RangeSetOf[K, int]
+}
+type SpanCounter = SpanCounterOf[int]
+
+func addDepth(depth, count *int) int {
+	return *depth + *count
+}
+
+// addDepths returns the sum of two depth functions, dropping ranges of depth
+// zero and coalescing adjacent ranges of equal depth.
+func addDepths[K Integer](s, t RangeSetOf[K, int]) RangeSetOf[K, int] {
+	depths := RangeSetOf[K, int]{}
+	var last *spanValue[K, int]
+	s.DoCover(t, addDepth, func(span SpanOf[K], depth int) bool {
+		if depth == 0 {
+			return true
+		}
+		if last != nil && last.span[1] == span[0] && last.value == depth {
+			last.span[1] = span[1]
+		} else {
+			last = extend(&depths.set.items, span, depth)
+		}
+		return true
+	})
+	depths.set.check()
+	return depths
+}
+
+// depthsOf returns count times the depth function of spans. The halves are
+// summed recursively, so each boundary takes part in O(log n) sweeps.
+func depthsOf[K Integer](spans []SpanOf[K], count int) RangeSetOf[K, int] {
+	switch len(spans) {
+	case 0:
+		return RangeSetOf[K, int]{}
+	case 1:
+		depths := RangeSetOf[K, int]{}
+		if !spans[0].Empty() {
+			depths.set.extend(spans[0], count)
+		}
+		return depths
+	}
+	middle := len(spans) / 2
+	return addDepths(depthsOf(spans[:middle], count), depthsOf(spans[middle:], count))
+}
+
+// Add counts a span.
+func (c *SpanCounterOf[K]) Add(span SpanOf[K]) {
+	c.depths = addDepths(c.depths, depthsOf([]SpanOf[K]{span}, 1))
+}
+
+// AddAll counts several spans.
+func (c *SpanCounterOf[K]) AddAll(spans ...SpanOf[K]) {
+	c.depths = addDepths(c.depths, depthsOf(spans, 1))
+}
+
+// Remove uncounts a span which was previously added.
+func (c *SpanCounterOf[K]) Remove(span SpanOf[K]) {
+	c.depths = addDepths(c.depths, depthsOf([]SpanOf[K]{span}, -1))
+}
+
+// RemoveAll uncounts several spans which were previously added.
+func (c *SpanCounterOf[K]) RemoveAll(spans ...SpanOf[K]) {
+	c.depths = addDepths(c.depths, depthsOf(spans, -1))
+}
+
+// Depth returns the number of spans covering a point.
+func (c SpanCounterOf[K]) Depth(key K) int {
+	if depth := c.depths.Get(key); depth != nil {
+		return *depth
+	}
+	return 0
+}
+
+// MaxDepth returns the greatest depth and the ranges where it is reached.
+func (c SpanCounterOf[K]) MaxDepth() (int, SpanSetOf[K]) {
+	maxDepth := 0
+	for info := range c.depths.set.from(0) {
+		maxDepth = max(maxDepth, info.value)
+	}
+	where := SpanSetOf[K]{}
+	for info := range c.depths.set.from(0) {
+		if info.value == maxDepth {
+			where.set.extend(info.span, struct{}{})
+		}
+	}
+	return maxDepth, where
+}
+
+// Depths returns a copy of the depth function as ranges of constant non-zero
+// depth. Adjacent ranges have different depths.
+func (c SpanCounterOf[K]) Depths() RangeSetOf[K, int] {
+	return c.depths.Clone()
+}
+
+func (c SpanCounterOf[K]) String() string {
+	return c.depths.String()
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSpanCounter(t *testing.T) {
+	spans := []Span{{0, 10}, {5, 15}, {5, 8}, {20, 25}, {7, 9}, {25, 30}}
+	counter := SpanCounter{}
+	for _, span := range spans {
+		counter.Add(span)
+	}
+	for point := -2; point < 32; point++ {
+		expected := 0
+		for _, span := range spans {
+			if span.Contains(point) {
+				expected++
+			}
+		}
+		if depth := counter.Depth(point); depth != expected {
+			t.Errorf("Depth(%d): expected %d, got %d", point, expected, depth)
+		}
+	}
+	if depth, where := counter.MaxDepth(); depth != 4 || where.String() != "{ [0]=[7,8)={} }" {
+		t.Errorf("MaxDepth: expected 4 at [7,8), got %d at %s", depth, where)
+	}
+	depths := counter.Depths()
+	if depths.set.len() != 7 || depths.Get(22) == nil || depths.GetRange(22).Span != (Span{20, 30}) {
+		t.Errorf("Depths: expected 7 ranges with [20,30) coalesced, got %s", depths)
+	}
+
+	counter.Remove(Span{7, 9})
+	counter.Remove(Span{20, 25})
+	for _, test := range []struct{ point, depth int }{{7, 3}, {8, 2}, {22, 0}, {27, 1}} {
+		if depth := counter.Depth(test.point); depth != test.depth {
+			t.Errorf("Remove: Depth(%d): expected %d, got %d", test.point, test.depth, depth)
+		}
+	}
+	if depth, where := counter.MaxDepth(); depth != 3 || where.String() != "{ [0]=[5,8)={} }" {
+		t.Errorf("MaxDepth after Remove: expected 3 at [5,8), got %d at %s", depth, where)
+	}
+}
+
+func TestSpanCounterAll(t *testing.T) {
+	spans := make([]Span, 0)
+	for index, span := range randomSpans(500, 3) {
+		spans = append(spans, Span{span[0], span[1] + index%11})
+	}
+	one, all := SpanCounter{}, SpanCounter{}
+	for _, span := range spans {
+		one.Add(span)
+	}
+	all.AddAll(spans...)
+	if one.String() != all.String() {
+		t.Errorf("AddAll: expected %s, got %s", one, all)
+	}
+	all.RemoveAll(spans[100:]...)
+	for _, span := range spans[100:] {
+		one.Remove(span)
+	}
+	if one.String() != all.String() {
+		t.Errorf("RemoveAll: expected %s, got %s", one, all)
+	}
+}
+
+func TestSpanCounterCopy(t *testing.T) {
+	a := SpanCounter{}
+	a.Add(Span{0, 10})
+	a.Depth(0)
+	b := a
+	b.Add(Span{0, 10})
+	a.Add(Span{20, 30})
+	if depth := a.Depth(0); depth != 1 {
+		t.Errorf("Depth(0): expected 1 after adding to a copy, got %d", depth)
+	}
+	if depth := b.Depth(0); depth != 2 {
+		t.Errorf("Depth(0) of copy: expected 2, got %d", depth)
+	}
+	if depth := b.Depth(25); depth != 0 {
+		t.Errorf("Depth(25) of copy: expected 0 after adding to the original, got %d", depth)
+	}
+	if text := fmt.Sprint(a); text != a.String() {
+		t.Errorf("Sprint: expected %s, got %s", a.String(), text)
+	}
+}
+
+func BenchmarkSpanCounter(b *testing.B) {
+	spans := make([]Span, 0)
+	for index, span := range randomSpans(10000, 1) {
+		spans = append(spans, Span{span[0], span[1] + index%7})
+	}
+	for range b.N {
+		counter := SpanCounter{}
+		counter.AddAll(spans...)
+		counter.MaxDepth()
+	}
+}
@@ -0,0 +1,48 @@
+package util
+
+// DoCoverAll sweeps several sets at once, visiting each segment covered by at
+// least one of them with the values active on it, indexed by set. The value
+// of a set which does not cover the segment is nil.
+//
+// The values slice is reused between visits. If a visit returns false, no
+// more segments are visited.
+func DoCoverAll[K Integer, T any](sets []RangeSetOf[K, T], visit func(SpanOf[K], []*T) bool) {
+	values := make([]*T, len(sets))
+	Sweep(sets, func(span SpanOf[K], active []RangeResultOf[K, T]) bool {
+		for index := range active {
+			values[index] = active[index].Value
+		}
+		return visit(span, values)
+	})
+}
+
+// CoverAll returns the union of several sets. Each segment takes the value of
+// combine applied to the values active on it, as for DoCoverAll.
+//
+// Segments are split wherever any set's ranges begin or end.
+func CoverAll[K Integer, T any](sets []RangeSetOf[K, T], combine func(values []*T) T) RangeSetOf[K, T] {
+	result := RangeSetOf[K, T]{}
+	DoCoverAll(sets, func(span SpanOf[K], values []*T) bool {
+		result.set.extend(span, combine(values))
+		return true
+	})
+	result.set.check()
+	return result
+}
+
+// IntersectAll returns the segments covered by every one of several sets,
+// valued by combine applied to the values of each set on it.
+func IntersectAll[K Integer, T any](sets []RangeSetOf[K, T], combine func(values []*T) T) RangeSetOf[K, T] {
+	result := RangeSetOf[K, T]{}
+	DoCoverAll(sets, func(span SpanOf[K], values []*T) bool {
+		for _, value := range values {
+			if value == nil {
+				return true
+			}
+		}
+		result.set.extend(span, combine(values))
+		return true
+	})
+	result.set.check()
+	return result
+}
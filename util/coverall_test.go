@@ -0,0 +1,75 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCoverAll(t *testing.T) {
+	sets := make([]RangeSet[int], 3)
+	sets[0].Add(Span{0, 10}, 1)
+	sets[1].Add(Span{5, 15}, 2)
+	sets[1].Add(Span{20, 25}, 3)
+	sets[2] = NewRangeSet[int, int](TreeBackend)
+	sets[2].Add(Span{8, 20}, 4)
+	sets[2].Add(Span{-5, -1}, 5)
+
+	// Pairwise Cover with a summing combine gives the same segments.
+	add := func(x, y *int) int {
+		return *x + *y
+	}
+	expected := sets[0].Cover(sets[1], add).Cover(sets[2], add)
+	cover := CoverAll(sets, func(values []*int) int {
+		total := 0
+		for _, value := range values {
+			if value != nil {
+				total += *value
+			}
+		}
+		return total
+	})
+	if cover.String() != expected.String() {
+		t.Errorf("CoverAll: expected %s, got %s", expected, cover)
+	}
+
+	intersect := IntersectAll(sets, func(values []*int) int {
+		return *values[0]*100 + *values[1]*10 + *values[2]
+	})
+	if intersect.String() != "{ [0]=[8,10)=124 }" {
+		t.Errorf("IntersectAll: expected { [0]=[8,10)=124 }, got %s", intersect)
+	}
+}
+
+func BenchmarkCoverAll(b *testing.B) {
+	sets := make([]RangeSet[int], 32)
+	for index := range sets {
+		for _, span := range randomSpans(256, uint64(index)) {
+			sets[index].Add(Span{span[0] + index, span[1] + index}, index)
+		}
+	}
+	add := func(x, y *int) int {
+		return *x + *y
+	}
+	sum := func(values []*int) int {
+		total := 0
+		for _, value := range values {
+			if value != nil {
+				total += *value
+			}
+		}
+		return total
+	}
+	b.Run(fmt.Sprintf("pairwise/%d", len(sets)), func(b *testing.B) {
+		for range b.N {
+			cover := sets[0]
+			for _, set := range sets[1:] {
+				cover = cover.Cover(set, add)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("heap/%d", len(sets)), func(b *testing.B) {
+		for range b.N {
+			CoverAll(sets, sum)
+		}
+	})
+}
@@ -0,0 +1,6 @@
+//go:build rangesetdebug
+
+package util
+
+// debugChecks enables validation of range sets after every mutation.
+const debugChecks = true
@@ -0,0 +1,417 @@
+package util
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+const (
+	chunkBits  = 16
+	chunkSize  = 1 << chunkBits
+	chunkWords = chunkSize / 64
+	// maxRuns is the number of runs above which a chunk switches to a
+	// bitmap: 682 runs of 12 bytes take as much space as the 8KB bitmap.
+	maxRuns = chunkSize / 8 / 12
+)
+
+// IntSetOf is a set of integers, stored like a roaring bitmap.
+//
+// The values are split into chunks of 2^16 by their high bits. Each chunk
+// keeps its members either as runs in a SpanSet or as a bitmap, whichever
+// is smaller: a chunk switches to a bitmap when it has more than 682 runs,
+// and back to runs when it has 341 members or fewer, or on Optimize. Dense
+// runs of values such as ranges of IDs cost a few bytes however long they
+// are, while scattered values cost at most a bit each.
+//
+// Like SpanOf, the set cannot hold the largest value of K. The zero value
+// is an empty set.
+type IntSetOf[K Integer] struct {
+	chunks []intChunk[K]
+}
+type IntSet = IntSetOf[int]
+
+type intChunk[K Integer] struct {
+	key    K // the high bits of the values in the chunk
+	count  int
+	runs   SpanSetOf[int32] // offsets of members within the chunk
+	bitmap *[chunkWords]uint64
+}
+
+// chunkShift is chunkBits as a variable: vet rejects constant shifts wider
+// than K, but Go defines them, and chunkKey and value rely on the result.
+var chunkShift uint = chunkBits
+
+func chunkKey[K Integer](value K) K {
+	return value >> chunkShift
+}
+
+func chunkOffset[K Integer](value K) int32 {
+	return int32(uint64(value) & (chunkSize - 1))
+}
+
+// value returns the member at an offset in the chunk. The arithmetic wraps
+// for K narrower than the chunk, which maps offsets back to the same values.
+func (c *intChunk[K]) value(offset int32) K {
+	return c.key<<chunkShift + K(offset)
+}
+
+func (c *intChunk[K]) contains(offset int32) bool {
+	if c.bitmap != nil {
+		return c.bitmap[offset/64]>>(offset%64)&1 != 0
+	}
+	return c.runs.Get(offset) != nil
+}
+
+// setBits sets or clears the bits in [lo,hi) and returns the change in count.
+func setBits(bitmap *[chunkWords]uint64, lo, hi int32, on bool) int {
+	change := 0
+	for lo < hi {
+		n := min(64-lo%64, hi-lo)
+		mask := (^uint64(0) >> (64 - n)) << (lo % 64)
+		word := &bitmap[lo/64]
+		before := bits.OnesCount64(*word)
+		if on {
+			*word |= mask
+		} else {
+			*word &^= mask
+		}
+		change += bits.OnesCount64(*word) - before
+		lo += n
+	}
+	return change
+}
+
+// bitRuns returns the runs of set bits in a bitmap.
+func bitRuns(bitmap *[chunkWords]uint64) SpanSetOf[int32] {
+	runs := SpanSetOf[int32]{}
+	start := int32(-1)
+	for index, word := range bitmap {
+		base := int32(index * 64)
+		switch {
+		case word == 0 && start < 0, word == ^uint64(0) && start >= 0:
+			continue
+		}
+		for bit := range int32(64) {
+			if on := word>>bit&1 != 0; on && start < 0 {
+				start = base + bit
+			} else if !on && start >= 0 {
+				runs.set.extend(SpanOf[int32]{start, base + bit}, struct{}{})
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		runs.set.extend(SpanOf[int32]{start, chunkSize}, struct{}{})
+	}
+	return runs
+}
+
+// runSet returns the members of the chunk as runs.
+func (c *intChunk[K]) runSet() SpanSetOf[int32] {
+	if c.bitmap != nil {
+		return bitRuns(c.bitmap)
+	}
+	return c.runs
+}
+
+// words returns the members of the chunk as a bitmap, which may be shared.
+func (c *intChunk[K]) words() *[chunkWords]uint64 {
+	if c.bitmap != nil {
+		return c.bitmap
+	}
+	bitmap := new([chunkWords]uint64)
+	for span := range c.runs.set.from(0) {
+		setBits(bitmap, span.span[0], span.span[1], true)
+	}
+	return bitmap
+}
+
+// optimize switches the chunk to whichever storage suits it.
+func (c *intChunk[K]) optimize() {
+	switch {
+	case c.bitmap == nil && c.runs.set.len() > maxRuns:
+		c.bitmap = c.words()
+		c.runs = SpanSetOf[int32]{}
+	case c.bitmap != nil && c.count <= maxRuns/2:
+		c.runs = bitRuns(c.bitmap)
+		c.bitmap = nil
+	}
+}
+
+func keepRun(x, y *struct{}) bool {
+	return true
+}
+
+// update adds or removes the offsets in [lo,hi).
+func (c *intChunk[K]) update(lo, hi int32, on bool) {
+	if c.bitmap != nil {
+		c.count += setBits(c.bitmap, lo, hi, on)
+		c.optimize()
+		return
+	}
+	// Runs are separated by gaps, so the runs which touch the span are the
+	// ones to merge with it.
+	span := SpanOf[int32]{lo, hi}
+	start, end := c.runs.set.overlapping(SpanOf[int32]{lo - 1, hi + 1})
+	covered := 0
+	for index := start; index < end; index++ {
+		covered += int(c.runs.set.at(index).span.Intersect(span).Len())
+	}
+	switch {
+	case on && covered == int(span.Len()), !on && covered == 0:
+		return
+	case on:
+		if start < end {
+			span = SpanOf[int32]{min(lo, c.runs.set.at(start).span[0]), max(hi, c.runs.set.at(end - 1).span[1])}
+		}
+		c.runs.set.replace(start, end, spanValue[int32, struct{}]{span: span})
+		c.count += int(hi-lo) - covered
+	default:
+		rest := make([]spanValue[int32, struct{}], 0, 2)
+		if first := c.runs.set.at(start).span; first[0] < lo {
+			rest = append(rest, spanValue[int32, struct{}]{span: SpanOf[int32]{first[0], lo}})
+		}
+		if last := c.runs.set.at(end - 1).span; last[1] > hi {
+			rest = append(rest, spanValue[int32, struct{}]{span: SpanOf[int32]{hi, last[1]}})
+		}
+		c.runs.set.replace(start, end, rest...)
+		c.count -= covered
+	}
+	c.runs.set.check()
+	c.optimize()
+}
+
+func chunkFromRuns[K Integer](key K, runs SpanSetOf[int32]) intChunk[K] {
+	chunk := intChunk[K]{key: key, runs: runs, count: int(runs.Measure())}
+	chunk.optimize()
+	return chunk
+}
+
+func chunkFromWords[K Integer](key K, bitmap *[chunkWords]uint64) intChunk[K] {
+	chunk := intChunk[K]{key: key, bitmap: bitmap}
+	for _, word := range bitmap {
+		chunk.count += bits.OnesCount64(word)
+	}
+	chunk.optimize()
+	return chunk
+}
+
+// find returns the index of the chunk for a key, and whether it exists.
+func (s *IntSetOf[K]) find(key K) (int, bool) {
+	return slices.BinarySearchFunc(s.chunks, key, func(chunk intChunk[K], key K) int {
+		return cmp.Compare(chunk.key, key)
+	})
+}
+
+// update adds or removes the values in a span, chunk by chunk.
+func (s *IntSetOf[K]) update(span SpanOf[K], on bool) {
+	for lo := span[0]; lo < span[1]; {
+		offset := chunkOffset(lo)
+		// Widths are taken in uint64, which cannot overflow for any K.
+		n := min(uint64(chunkSize-offset), uint64(span[1])-uint64(lo))
+		index, found := s.find(chunkKey(lo))
+		if !found && on {
+			s.chunks = slices.Insert(s.chunks, index, intChunk[K]{key: chunkKey(lo)})
+			found = true
+		}
+		if found {
+			s.chunks[index].update(offset, offset+int32(n), on)
+			if s.chunks[index].count == 0 {
+				s.chunks = slices.Delete(s.chunks, index, index+1)
+			}
+		}
+		lo += K(n)
+	}
+}
+
+func (s *IntSetOf[K]) Add(value K) {
+	s.update(SpanOf[K]{value, value + 1}, true)
+}
+
+// AddSpan adds every value in a span.
+func (s *IntSetOf[K]) AddSpan(span SpanOf[K]) {
+	s.update(span, true)
+}
+
+func (s *IntSetOf[K]) Remove(value K) {
+	s.update(SpanOf[K]{value, value + 1}, false)
+}
+
+// RemoveSpan removes every value in a span.
+func (s *IntSetOf[K]) RemoveSpan(span SpanOf[K]) {
+	s.update(span, false)
+}
+
+func (s IntSetOf[K]) Contains(value K) bool {
+	index, found := s.find(chunkKey(value))
+	return found && s.chunks[index].contains(chunkOffset(value))
+}
+
+// Len returns the number of members.
+func (s IntSetOf[K]) Len() int {
+	count := 0
+	for _, chunk := range s.chunks {
+		count += chunk.count
+	}
+	return count
+}
+
+// Optimize switches bitmap chunks with few runs back to runs.
+func (s *IntSetOf[K]) Optimize() {
+	for index := range s.chunks {
+		chunk := &s.chunks[index]
+		if chunk.bitmap != nil {
+			if runs := bitRuns(chunk.bitmap); runs.set.len() <= maxRuns/2 {
+				chunk.runs = runs
+				chunk.bitmap = nil
+			}
+		}
+	}
+}
+
+// Runs returns an iterator over the maximal spans of consecutive members.
+func (s IntSetOf[K]) Runs() iter.Seq[SpanOf[K]] {
+	return func(yield func(SpanOf[K]) bool) {
+		var run SpanOf[K]
+		started := false
+		for index := range s.chunks {
+			chunk := &s.chunks[index]
+			for span := range chunk.runSet().All() {
+				lo := chunk.value(span[0])
+				hi := lo + K(span.Len())
+				if started && run[1] == lo {
+					run[1] = hi
+					continue
+				}
+				if started && !yield(run) {
+					return
+				}
+				run, started = SpanOf[K]{lo, hi}, true
+			}
+		}
+		if started {
+			yield(run)
+		}
+	}
+}
+
+// All returns an iterator over the members in order.
+func (s IntSetOf[K]) All() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for run := range s.Runs() {
+			for value := run[0]; value < run[1]; value++ {
+				if !yield(value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SpanSet returns the runs of the set as a SpanSet.
+func (s IntSetOf[K]) SpanSet() SpanSetOf[K] {
+	result := SpanSetOf[K]{}
+	for run := range s.Runs() {
+		result.set.extend(run, struct{}{})
+	}
+	return result
+}
+
+// combine merges the chunks of two sets. Where both sets have a chunk, it
+// calls runs or words depending on whether either chunk is a bitmap. Chunks
+// only in s or t are kept if keepS or keepT is true.
+func (s IntSetOf[K]) combine(t IntSetOf[K], keepS, keepT bool,
+	runs func(x, y SpanSetOf[int32]) SpanSetOf[int32],
+	words func(x, y, result *[chunkWords]uint64)) IntSetOf[K] {
+	result := IntSetOf[K]{}
+	sIndex, tIndex := 0, 0
+	for sIndex < len(s.chunks) || tIndex < len(t.chunks) {
+		switch {
+		case tIndex == len(t.chunks) || (sIndex < len(s.chunks) && s.chunks[sIndex].key < t.chunks[tIndex].key):
+			if keepS {
+				result.chunks = append(result.chunks, s.chunks[sIndex].clone())
+			}
+			sIndex++
+		case sIndex == len(s.chunks) || t.chunks[tIndex].key < s.chunks[sIndex].key:
+			if keepT {
+				result.chunks = append(result.chunks, t.chunks[tIndex].clone())
+			}
+			tIndex++
+		default:
+			x, y := &s.chunks[sIndex], &t.chunks[tIndex]
+			var chunk intChunk[K]
+			if x.bitmap == nil && y.bitmap == nil {
+				chunk = chunkFromRuns(x.key, runs(x.runs, y.runs))
+			} else {
+				bitmap := new([chunkWords]uint64)
+				words(x.words(), y.words(), bitmap)
+				chunk = chunkFromWords(x.key, bitmap)
+			}
+			if chunk.count > 0 {
+				result.chunks = append(result.chunks, chunk)
+			}
+			sIndex++
+			tIndex++
+		}
+	}
+	return result
+}
+
+// clone returns a copy of the chunk which shares no storage with it, since
+// chunks are updated in place.
+func (c intChunk[K]) clone() intChunk[K] {
+	if c.bitmap != nil {
+		bitmap := *c.bitmap
+		c.bitmap = &bitmap
+	}
+	c.runs = c.runs.Clone()
+	return c
+}
+
+func (s IntSetOf[K]) Union(t IntSetOf[K]) IntSetOf[K] {
+	return s.combine(t, true, true, func(x, y SpanSetOf[int32]) SpanSetOf[int32] {
+		return x.Union(y).Normalize(keepRun)
+	}, func(x, y, result *[chunkWords]uint64) {
+		for index := range result {
+			result[index] = x[index] | y[index]
+		}
+	})
+}
+
+func (s IntSetOf[K]) Intersect(t IntSetOf[K]) IntSetOf[K] {
+	return s.combine(t, false, false, func(x, y SpanSetOf[int32]) SpanSetOf[int32] {
+		return x.Intersect(y, func(_, _ *struct{}) struct{} { return struct{}{} })
+	}, func(x, y, result *[chunkWords]uint64) {
+		for index := range result {
+			result[index] = x[index] & y[index]
+		}
+	})
+}
+
+// Difference returns the members of s which are not in t.
+func (s IntSetOf[K]) Difference(t IntSetOf[K]) IntSetOf[K] {
+	return s.combine(t, true, false, func(x, y SpanSetOf[int32]) SpanSetOf[int32] {
+		return x.Difference(y)
+	}, func(x, y, result *[chunkWords]uint64) {
+		for index := range result {
+			result[index] = x[index] &^ y[index]
+		}
+	})
+}
+
+// String lists the runs of the set, such as "{1-3, 7, 10-19}".
+func (s IntSetOf[K]) String() string {
+	runs := make([]string, 0)
+	for run := range s.Runs() {
+		if run[1]-run[0] == 1 {
+			runs = append(runs, fmt.Sprint(run[0]))
+		} else {
+			runs = append(runs, fmt.Sprintf("%d-%d", run[0], run[1]-1))
+		}
+	}
+	return "{" + strings.Join(runs, ", ") + "}"
+}
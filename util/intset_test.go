@@ -0,0 +1,156 @@
+package util
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func checkIntSet(t *testing.T, name string, set IntSet, model Set[int], lo, hi int) {
+	t.Helper()
+	members := make([]int, 0)
+	for point := lo; point < hi; point++ {
+		if model[point] {
+			members = append(members, point)
+		}
+		if set.Contains(point) != model[point] {
+			t.Fatalf("%s: Contains(%d): expected %t", name, point, model[point])
+		}
+	}
+	if got := slices.Collect(set.All()); !slices.Equal(got, members) || set.Len() != len(members) {
+		t.Fatalf("%s: expected %d members, got %d (Len %d)", name, len(members), len(got), set.Len())
+	}
+	last := SpanOf[int]{lo - 2, lo - 2}
+	for run := range set.Runs() {
+		if run.Empty() || run[0] <= last[1] {
+			t.Fatalf("%s: run %s is empty or not maximal after %s", name, run, last)
+		}
+		last = run
+	}
+}
+
+func TestIntSet(t *testing.T) {
+	random := rand.New(rand.NewPCG(7, 8))
+	const lo, hi = -70000, 140000
+	sets := [2]IntSet{}
+	models := [2]Set[int]{{}, {}}
+	for step := 0; step < 4000; step++ {
+		which := random.IntN(2)
+		start := lo + random.IntN(hi-lo)
+		span := Span{start, min(hi, start+1+random.IntN(3))}
+		if random.IntN(50) == 0 {
+			span[1] = min(hi, start+random.IntN(100000))
+		}
+		on := random.IntN(3) > 0
+		if on {
+			sets[which].AddSpan(span)
+		} else {
+			sets[which].RemoveSpan(span)
+		}
+		for point := span[0]; point < span[1]; point++ {
+			if on {
+				models[which][point] = true
+			} else {
+				delete(models[which], point)
+			}
+		}
+	}
+	for which := range sets {
+		checkIntSet(t, "AddSpan", sets[which], models[which], lo, hi)
+	}
+
+	union, intersect, difference := Set[int]{}, Set[int]{}, Set[int]{}
+	for point := lo; point < hi; point++ {
+		s, u := models[0][point], models[1][point]
+		union[point] = s || u
+		intersect[point] = s && u
+		difference[point] = s && !u
+	}
+	checkIntSet(t, "Union", sets[0].Union(sets[1]), union, lo, hi)
+	checkIntSet(t, "Intersect", sets[0].Intersect(sets[1]), intersect, lo, hi)
+	checkIntSet(t, "Difference", sets[0].Difference(sets[1]), difference, lo, hi)
+	// The operands are unchanged.
+	checkIntSet(t, "Union operand", sets[0], models[0], lo, hi)
+}
+
+func TestIntSetStorage(t *testing.T) {
+	set := IntSet{}
+	set.AddSpan(Span{0, 1 << 20})
+	if len(set.chunks) != 16 || set.chunks[0].bitmap != nil || set.chunks[0].runs.set.len() != 1 {
+		t.Errorf("AddSpan: expected 16 chunks of one run")
+	}
+	for value := 0; value < chunkSize; value += 2 {
+		set.Remove(value)
+	}
+	if set.chunks[0].bitmap == nil || set.Len() != 15*chunkSize+chunkSize/2 {
+		t.Errorf("Remove: expected a bitmap chunk, got %d runs", set.chunks[0].runs.set.len())
+	}
+	set.RemoveSpan(Span{0, chunkSize - 100})
+	if set.chunks[0].bitmap != nil || set.chunks[0].count != 50 {
+		t.Errorf("RemoveSpan: expected runs for 50 members, got count %d", set.chunks[0].count)
+	}
+	set.AddSpan(Span{0, chunkSize})
+	for value := 1; value < 2000; value += 2 {
+		set.Remove(value)
+	}
+	set.AddSpan(Span{0, 2000})
+	set.Optimize()
+	if set.chunks[0].bitmap != nil || set.String() != "{0-1048575}" {
+		t.Errorf("Optimize: expected one run, got %s", set)
+	}
+
+	narrow := IntSetOf[int8]{}
+	narrow.AddSpan(SpanOf[int8]{-128, 127})
+	narrow.RemoveSpan(SpanOf[int8]{-3, 3})
+	if narrow.String() != "{-128--4, 3-126}" || narrow.Len() != 249 || !narrow.Contains(-128) || narrow.Contains(0) {
+		t.Errorf("IntSetOf[int8]: got %s", narrow)
+	}
+	wide := IntSetOf[uint64]{}
+	wide.AddSpan(SpanOf[uint64]{1<<63 - 5, 1<<63 + 5})
+	wide.Add(3)
+	if wide.Len() != 11 || !wide.Contains(1<<63) || len(wide.SpanSet().set.items) != 2 {
+		t.Errorf("IntSetOf[uint64]: got %s", wide)
+	}
+}
+
+func TestIntSetShared(t *testing.T) {
+	// Chunks are updated in place, so results must not share them with
+	// their operands.
+	s, u := IntSet{}, IntSet{}
+	s.AddSpan(Span{0, 10})
+	u.AddSpan(Span{1 << 20, 1<<20 + 10})
+	union := s.Union(u)
+	s.AddSpan(Span{20, 30})
+	u.Remove(1 << 20)
+	if union.String() != "{0-9, 1048576-1048585}" {
+		t.Errorf("Union: expected {0-9, 1048576-1048585} after updating operands, got %s", union)
+	}
+	union.Add(15)
+	if s.String() != "{0-9, 20-29}" {
+		t.Errorf("Union: expected operand {0-9, 20-29} after updating result, got %s", s)
+	}
+}
+
+func BenchmarkIntSetAdd(b *testing.B) {
+	random := rand.New(rand.NewPCG(7, 8))
+	values := make([]int, 8500)
+	for index := range values {
+		values[index] = random.IntN(1 << 20)
+	}
+	b.Run("IntSet", func(b *testing.B) {
+		for range b.N {
+			set := IntSet{}
+			for _, value := range values {
+				set.Add(value)
+			}
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		for range b.N {
+			set := map[int]bool{}
+			for _, value := range values {
+				set[value] = true
+			}
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package util
+
+import (
+	"iter"
+)
+
+// All returns an iterator over the ranges in the set in order.
+func (s RangeSetOf[K, T]) All() iter.Seq2[SpanOf[K], *T] {
+	return func(yield func(SpanOf[K], *T) bool) {
+		for info := range s.set.from(0) {
+			if !yield(info.span, &info.value) {
+				return
+			}
+		}
+	}
+}
+
+// From returns an iterator over the ranges in the set in order, starting
+// from the range containing key or the first range after it.
+func (s RangeSetOf[K, T]) From(key K) iter.Seq2[SpanOf[K], *T] {
+	return func(yield func(SpanOf[K], *T) bool) {
+		for info := range s.set.from(s.set.bisect(key)) {
+			if !yield(info.span, &info.value) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the ranges in the set in reverse order.
+func (s RangeSetOf[K, T]) Backward() iter.Seq2[SpanOf[K], *T] {
+	return func(yield func(SpanOf[K], *T) bool) {
+		for info := range s.set.backward(s.set.len()) {
+			if !yield(info.span, &info.value) {
+				return
+			}
+		}
+	}
+}
+
+// BackwardFrom returns an iterator over the ranges in the set in reverse
+// order, starting from the range containing key or the last range before it.
+func (s RangeSetOf[K, T]) BackwardFrom(key K) iter.Seq2[SpanOf[K], *T] {
+	return func(yield func(SpanOf[K], *T) bool) {
+		index := s.set.bisect(key)
+		if index < s.set.len() && s.set.at(index).span.Contains(key) {
+			index++
+		}
+		for info := range s.set.backward(index) {
+			if !yield(info.span, &info.value) {
+				return
+			}
+		}
+	}
+}
+
+// Intersections returns an iterator over the intersections of two sets.
+//
+// Each intersection is yielded with the values from s and t, in that order.
+func (s RangeSetOf[K, T]) Intersections(t RangeSetOf[K, T]) iter.Seq2[SpanOf[K], [2]*T] {
+	return func(yield func(SpanOf[K], [2]*T) bool) {
+		s.DoIntersect(t, func(_, _, ix SpanOf[K], svalue, tvalue *T) bool {
+			return yield(ix, [2]*T{svalue, tvalue})
+		})
+	}
+}
+
+// Covering returns an iterator over the cover set of two sets.
+//
+// See DoCover.
+func (s RangeSetOf[K, T]) Covering(t RangeSetOf[K, T], combine CombineFunc[T]) iter.Seq2[SpanOf[K], T] {
+	return func(yield func(SpanOf[K], T) bool) {
+		s.DoCover(t, combine, yield)
+	}
+}
+
+// All returns an iterator over the ranges in the map and their deltas.
+func (s RangeMapOf[K]) All() iter.Seq2[SpanOf[K], K] {
+	return func(yield func(SpanOf[K], K) bool) {
+		for span, delta := range RangeSetOf[K, K](s).All() {
+			if !yield(span, *delta) {
+				return
+			}
+		}
+	}
+}
+
+// From returns an iterator over the ranges in the map and their deltas,
+// starting from the range containing key or the first range after it.
+func (s RangeMapOf[K]) From(key K) iter.Seq2[SpanOf[K], K] {
+	return func(yield func(SpanOf[K], K) bool) {
+		for span, delta := range RangeSetOf[K, K](s).From(key) {
+			if !yield(span, *delta) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the ranges in the map and their deltas
+// in reverse order.
+func (s RangeMapOf[K]) Backward() iter.Seq2[SpanOf[K], K] {
+	return func(yield func(SpanOf[K], K) bool) {
+		for span, delta := range RangeSetOf[K, K](s).Backward() {
+			if !yield(span, *delta) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func collectSpans[V any](seq func(func(Span, V) bool), limit int) []Span {
+	spans := make([]Span, 0)
+	for span := range seq {
+		if len(spans) == limit {
+			break
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+func TestIterators(t *testing.T) {
+	entries := []spanValue[int, int]{
+		{Span{0, 5}, 1},
+		{Span{5, 10}, 2},
+		{Span{20, 30}, 3},
+		{Span{40, 45}, 4},
+	}
+	other := makeRangeSet(spanValue[int, int]{Span{3, 22}, 5}, spanValue[int, int]{Span{44, 50}, 6})
+	sum := func(x, y *int) int { return *x + *y }
+	for _, backend := range []Backend{SliceBackend, TreeBackend} {
+		set := makeRangeSetWith(backend, entries...)
+		type test struct {
+			name     string
+			spans    []Span
+			expected []Span
+		}
+		for _, test := range []test{
+			{"All", collectSpans(set.All(), -1), []Span{{0, 5}, {5, 10}, {20, 30}, {40, 45}}},
+			{"All", collectSpans(set.All(), 2), []Span{{0, 5}, {5, 10}}},
+			{"From", collectSpans(set.From(7), -1), []Span{{5, 10}, {20, 30}, {40, 45}}},
+			{"From", collectSpans(set.From(10), -1), []Span{{20, 30}, {40, 45}}},
+			{"From", collectSpans(set.From(50), -1), []Span{}},
+			{"Backward", collectSpans(set.Backward(), -1), []Span{{40, 45}, {20, 30}, {5, 10}, {0, 5}}},
+			{"Backward", collectSpans(set.Backward(), 1), []Span{{40, 45}}},
+			{"BackwardFrom", collectSpans(set.BackwardFrom(20), -1), []Span{{20, 30}, {5, 10}, {0, 5}}},
+			{"BackwardFrom", collectSpans(set.BackwardFrom(15), -1), []Span{{5, 10}, {0, 5}}},
+			{"BackwardFrom", collectSpans(set.BackwardFrom(-1), -1), []Span{}},
+			{"Intersections", collectSpans(set.Intersections(other), -1), []Span{{3, 5}, {5, 10}, {20, 22}, {44, 45}}},
+			{"Intersections", collectSpans(set.Intersections(other), 3), []Span{{3, 5}, {5, 10}, {20, 22}}},
+			{"Covering", collectSpans(set.Covering(other, sum), 4), []Span{{0, 3}, {3, 5}, {5, 10}, {10, 20}}},
+		} {
+			if !reflect.DeepEqual(test.spans, test.expected) {
+				t.Errorf("%s with backend %d: expected %v, got %v", test.name, backend, test.expected, test.spans)
+			}
+		}
+	}
+}
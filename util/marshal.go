@@ -0,0 +1,152 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseInteger parses a decimal integer, checking that it fits in K.
+func parseInteger[K Integer](text string) (K, error) {
+	var zero K
+	if ^zero < 0 {
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err == nil && int64(K(value)) != value {
+			err = fmt.Errorf("parsing %q: value out of range", text)
+		}
+		return K(value), err
+	}
+	value, err := strconv.ParseUint(strings.TrimPrefix(text, "+"), 10, 64)
+	if err == nil && uint64(K(value)) != value {
+		err = fmt.Errorf("parsing %q: value out of range", text)
+	}
+	return K(value), err
+}
+
+// MarshalText encodes the span as "[a,b)".
+func (s SpanOf[K]) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a span from "[a,b)".
+func (s *SpanOf[K]) UnmarshalText(text []byte) error {
+	inner, ok := strings.CutPrefix(string(text), "[")
+	if ok {
+		inner, ok = strings.CutSuffix(inner, ")")
+	}
+	lo, hi, found := strings.Cut(inner, ",")
+	if !ok || !found {
+		return fmt.Errorf("invalid span %q", text)
+	}
+	var err error
+	if s[0], err = parseInteger[K](strings.TrimSpace(lo)); err != nil {
+		return fmt.Errorf("invalid span %q: %w", text, err)
+	}
+	if s[1], err = parseInteger[K](strings.TrimSpace(hi)); err != nil {
+		return fmt.Errorf("invalid span %q: %w", text, err)
+	}
+	return nil
+}
+
+// unmarshalLines decodes lines of the form "[a,b) value" into a set.
+func (s *RangeSetOf[K, T]) unmarshalLines(text []byte, parse func(string) (T, error)) error {
+	*s = NewRangeSet[K, T](s.Backend())
+	for number, line := range strings.Split(string(text), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		end := strings.IndexByte(line, ')') + 1
+		var span SpanOf[K]
+		if err := span.UnmarshalText([]byte(line[:end])); err != nil {
+			return fmt.Errorf("line %d: %w", number+1, err)
+		}
+		value, err := parse(strings.TrimSpace(line[end:]))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", number+1, err)
+		}
+		if err := s.AddWith(span, value, OverlapReject, nil); err != nil {
+			return fmt.Errorf("line %d: %w", number+1, err)
+		}
+	}
+	return nil
+}
+
+// MarshalText encodes the set with one "[a,b) value" line per range, where
+// each value is encoded as JSON.
+func (s RangeSetOf[K, T]) MarshalText() ([]byte, error) {
+	var buffer bytes.Buffer
+	for span, value := range s.All() {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buffer, "%s %s\n", span, encoded)
+	}
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalText decodes a set encoded by MarshalText.
+//
+// The set keeps its backend. Overlapping ranges are rejected.
+func (s *RangeSetOf[K, T]) UnmarshalText(text []byte) error {
+	return s.unmarshalLines(text, func(text string) (T, error) {
+		var value T
+		err := json.Unmarshal([]byte(text), &value)
+		return value, err
+	})
+}
+
+type jsonEntry[K Integer, T any] struct {
+	Span  SpanOf[K] `json:"span"`
+	Value T         `json:"value"`
+}
+
+// MarshalJSON encodes the set as an array of {"span": "[a,b)", "value": v}.
+func (s RangeSetOf[K, T]) MarshalJSON() ([]byte, error) {
+	entries := make([]jsonEntry[K, T], 0, s.set.len())
+	for span, value := range s.All() {
+		entries = append(entries, jsonEntry[K, T]{span, *value})
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON decodes a set encoded by MarshalJSON.
+//
+// The set keeps its backend. Overlapping ranges are rejected.
+func (s *RangeSetOf[K, T]) UnmarshalJSON(data []byte) error {
+	var entries []jsonEntry[K, T]
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	*s = NewRangeSet[K, T](s.Backend())
+	for _, entry := range entries {
+		if err := s.AddWith(entry.Span, entry.Value, OverlapReject, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalText encodes the map with one "[a,b) +delta" line per range.
+func (s RangeMapOf[K]) MarshalText() ([]byte, error) {
+	var buffer bytes.Buffer
+	for span, delta := range s.All() {
+		fmt.Fprintf(&buffer, "%s %+d\n", span, delta)
+	}
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalText decodes a map encoded by MarshalText.
+func (s *RangeMapOf[K]) UnmarshalText(text []byte) error {
+	return (*RangeSetOf[K, K])(s).unmarshalLines(text, parseInteger[K])
+}
+
+func (s RangeMapOf[K]) MarshalJSON() ([]byte, error) {
+	return RangeSetOf[K, K](s).MarshalJSON()
+}
+
+func (s *RangeMapOf[K]) UnmarshalJSON(data []byte) error {
+	return (*RangeSetOf[K, K])(s).UnmarshalJSON(data)
+}
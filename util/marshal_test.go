@@ -0,0 +1,97 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSpanText(t *testing.T) {
+	type test struct {
+		text  string
+		span  Span
+		valid bool
+	}
+	for _, test := range []test{
+		{"[0,5)", Span{0, 5}, true},
+		{"[-7, 12)", Span{-7, 12}, true},
+		{"[0,5]", Span{}, false},
+		{"0,5", Span{}, false},
+		{"[a,5)", Span{}, false},
+	} {
+		var span Span
+		err := span.UnmarshalText([]byte(test.text))
+		if (err == nil) != test.valid || (test.valid && span != test.span) {
+			t.Errorf("UnmarshalText(%q): expected %s (valid %t), got %s, %v", test.text, test.span, test.valid, span, err)
+		}
+	}
+	var span SpanOf[uint8]
+	if err := span.UnmarshalText([]byte("[0,256)")); err == nil {
+		t.Errorf("UnmarshalText: expected range error, got %s", span)
+	}
+}
+
+func TestRangeSetRoundTrip(t *testing.T) {
+	set := RangeSet[string]{}
+	set.Add(Span{0, 5}, "a b")
+	set.Add(Span{5, 10}, "")
+	set.Add(Span{-20, -10}, "c\n")
+
+	text, err := set.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: unexpected error %v", err)
+	}
+	decoded := NewRangeSet[int, string](TreeBackend)
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText(%q): unexpected error %v", text, err)
+	}
+	if decoded.Backend() != TreeBackend || !reflect.DeepEqual(set.set.slice(), decoded.set.slice()) {
+		t.Errorf("UnmarshalText(%q): expected %s, got %s", text, set, decoded)
+	}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("MarshalJSON: unexpected error %v", err)
+	}
+	decoded = RangeSet[string]{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("UnmarshalJSON(%s): unexpected error %v", data, err)
+	}
+	if !reflect.DeepEqual(set.set.slice(), decoded.set.slice()) {
+		t.Errorf("UnmarshalJSON(%s): expected %s, got %s", data, set, decoded)
+	}
+
+	if err := decoded.UnmarshalText([]byte("[0,5) 1\n[4,6) 2\n")); err == nil {
+		t.Errorf("UnmarshalText: expected overlap error, got %s", decoded)
+	}
+}
+
+func TestRangeMapRoundTrip(t *testing.T) {
+	rangeMap := RangeMapOf[uint64]{}
+	rangeMap.Add(SpanOf[uint64]{0, 10}, 1<<63)
+	rangeMap.Add(SpanOf[uint64]{20, 30}, 3)
+
+	text, err := rangeMap.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: unexpected error %v", err)
+	}
+	var decoded RangeMapOf[uint64]
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText(%q): unexpected error %v", text, err)
+	}
+	if !reflect.DeepEqual(rangeMap.set.slice(), decoded.set.slice()) {
+		t.Errorf("UnmarshalText(%q): expected %s, got %s", text, rangeMap, decoded)
+	}
+
+	data, err := json.Marshal(map[string]RangeMapOf[uint64]{"seed": rangeMap})
+	if err != nil {
+		t.Fatalf("MarshalJSON: unexpected error %v", err)
+	}
+	var decodedMaps map[string]RangeMapOf[uint64]
+	if err := json.Unmarshal(data, &decodedMaps); err != nil {
+		t.Fatalf("UnmarshalJSON(%s): unexpected error %v", data, err)
+	}
+	if decoded := decodedMaps["seed"]; !reflect.DeepEqual(rangeMap.set.slice(), decoded.set.slice()) {
+		t.Errorf("UnmarshalJSON(%s): expected %s, got %s", data, rangeMap, decoded)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !rangesetdebug
+
+package util
+
+const debugChecks = false
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"iter"
+)
+
+// PersistentRangeSetOf is an immutable RangeSet.
+//
+// Updates return a new set and leave the old one unchanged. Both share the
+// ranges the update did not touch, so each version costs O(log n) to make
+// and any number of versions can be kept, for undo history or to branch a
+// search. Queries return copies of values, which are copied shallowly.
+//
+// The zero value is an empty set.
+type PersistentRangeSetOf[K Integer, T any] struct {
+	set RangeSetOf[K, T]
+}
+type PersistentRangeSet[T any] = PersistentRangeSetOf[int, T]
+
+// update applies a change to a copy of the set.
+func (p PersistentRangeSetOf[K, T]) update(change func(*spanStore[K, T]) error) (PersistentRangeSetOf[K, T], error) {
+	tree := &spanTree[K, T]{persistent: true}
+	if p.set.set.tree != nil {
+		tree.root = p.set.set.tree.root
+	}
+	result := PersistentRangeSetOf[K, T]{RangeSetOf[K, T]{spanStore[K, T]{tree: tree}}}
+	if err := change(&result.set.set); err != nil {
+		return p, err
+	}
+	return result, nil
+}
+
+// Add returns the set with a span inserted, without checking for overlaps.
+func (p PersistentRangeSetOf[K, T]) Add(span SpanOf[K], value T) PersistentRangeSetOf[K, T] {
+	result, _ := p.update(func(store *spanStore[K, T]) error {
+		store.add(span, value)
+		return nil
+	})
+	return result
+}
+
+// AddWith returns the set with a span inserted, resolving overlaps by policy.
+//
+// On error, it returns the set unchanged.
+func (p PersistentRangeSetOf[K, T]) AddWith(span SpanOf[K], value T, policy OverlapPolicy, combine CombineFunc[T]) (PersistentRangeSetOf[K, T], error) {
+	return p.update(func(store *spanStore[K, T]) error {
+		return store.addWith(span, value, policy, combine)
+	})
+}
+
+// Remove returns the set with a span deleted.
+func (p PersistentRangeSetOf[K, T]) Remove(span SpanOf[K]) PersistentRangeSetOf[K, T] {
+	result, _ := p.update(func(store *spanStore[K, T]) error {
+		store.remove(span)
+		return nil
+	})
+	return result
+}
+
+// Get returns the value of the range containing key.
+func (p PersistentRangeSetOf[K, T]) Get(key K) (T, bool) {
+	result, ok := entry(p.set.GetRange(key))
+	return result.Value, ok
+}
+
+// GetRange returns the range containing key.
+func (p PersistentRangeSetOf[K, T]) GetRange(key K) (Entry[K, T], bool) {
+	return entry(p.set.GetRange(key))
+}
+
+// Floor returns the range containing key or the last range before it.
+func (p PersistentRangeSetOf[K, T]) Floor(key K) (Entry[K, T], bool) {
+	return entry(p.set.Floor(key))
+}
+
+// Ceiling returns the range containing key or the first range after it.
+func (p PersistentRangeSetOf[K, T]) Ceiling(key K) (Entry[K, T], bool) {
+	return entry(p.set.Ceiling(key))
+}
+
+// Overlapping returns the ranges which overlap a span, in order.
+//
+// If clip is true, the returned spans are clipped to the span.
+func (p PersistentRangeSetOf[K, T]) Overlapping(span SpanOf[K], clip bool) []Entry[K, T] {
+	results := p.set.Overlapping(span, clip)
+	entries := make([]Entry[K, T], len(results))
+	for index, result := range results {
+		entries[index], _ = entry(&result)
+	}
+	return entries
+}
+
+// All returns an iterator over the ranges in the set in order.
+func (p PersistentRangeSetOf[K, T]) All() iter.Seq2[SpanOf[K], T] {
+	return func(yield func(SpanOf[K], T) bool) {
+		for info := range p.set.set.from(0) {
+			if !yield(info.span, info.value) {
+				return
+			}
+		}
+	}
+}
+
+func (p PersistentRangeSetOf[K, T]) Measure() K {
+	return p.set.Measure()
+}
+
+func (p PersistentRangeSetOf[K, T]) Span() SpanOf[K] {
+	return p.set.Span()
+}
+
+func (p PersistentRangeSetOf[K, T]) Len() int {
+	return p.set.set.len()
+}
+
+// RangeSet returns a mutable copy of the set using SliceBackend.
+func (p PersistentRangeSetOf[K, T]) RangeSet() RangeSetOf[K, T] {
+	return RangeSetOf[K, T]{spanStore[K, T]{items: p.set.set.slice()}}
+}
+
+func (p PersistentRangeSetOf[K, T]) Validate() error {
+	return p.set.Validate()
+}
+
+func (p PersistentRangeSetOf[K, T]) String() string {
+	return p.set.String()
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestPersistentRangeSet(t *testing.T) {
+	random := rand.New(rand.NewPCG(5, 6))
+	add := func(x, y *int) int {
+		return *x + *y
+	}
+	// Each version branches from a random earlier one; a mutable set replays
+	// the same change to give the expected contents.
+	versions := []PersistentRangeSet[int]{{}}
+	expected := [][]spanValue[int, int]{nil}
+	for step := 0; step < 500; step++ {
+		base := random.IntN(len(versions))
+		lo := random.IntN(200)
+		span := Span{lo, lo + 1 + random.IntN(20)}
+		value := random.IntN(100)
+		version := versions[base]
+		mutable := RangeSet[int]{set: spanStore[int, int]{items: append([]spanValue[int, int](nil), expected[base]...)}}
+		switch random.IntN(3) {
+		case 0:
+			version = version.Remove(span)
+			mutable.Remove(span)
+		case 1:
+			version, _ = version.AddWith(span, value, OverlapMerge, add)
+			mutable.AddWith(span, value, OverlapMerge, add)
+		default:
+			var err error
+			version, err = version.AddWith(span, value, OverlapReject, nil)
+			if mutable.AddWith(span, value, OverlapReject, nil) != nil && (err == nil || version.String() != versions[base].String()) {
+				t.Fatalf("AddWith(%s): expected ErrOverlap and an unchanged set, got %v", span, err)
+			}
+		}
+		versions = append(versions, version)
+		expected = append(expected, mutable.set.slice())
+	}
+	for index, version := range versions {
+		if err := version.Validate(); err != nil {
+			t.Fatalf("version %d: %v", index, err)
+		}
+		got := version.RangeSet()
+		if len(got.set.items)+len(expected[index]) > 0 && !reflect.DeepEqual(got.set.items, expected[index]) {
+			t.Errorf("version %d: expected %v, got %v", index, expected[index], got)
+		}
+	}
+
+	set := PersistentRangeSet[string]{}.Add(Span{0, 5}, "a").Add(Span{10, 15}, "b")
+	if value, ok := set.Get(12); !ok || value != "b" {
+		t.Errorf("Get(12): expected b, got %q, %t", value, ok)
+	}
+	if floor, ok := set.Floor(7); !ok || floor.Span != (Span{0, 5}) {
+		t.Errorf("Floor(7): expected [0,5), got %v, %t", floor, ok)
+	}
+	if entries := set.Overlapping(Span{3, 12}, true); len(entries) != 2 || entries[1] != (Entry[int, string]{Span{10, 12}, "b"}) {
+		t.Errorf("Overlapping([3,12)): expected [3,5) a and [10,12) b, got %v", entries)
+	}
+}
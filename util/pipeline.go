@@ -0,0 +1,155 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrCategory = errors.New("unknown category")
+
+// StageOf is one step of a pipeline: a map from one category to another.
+type StageOf[K Integer] struct {
+	Source      string
+	Destination string
+	Map         RangeMapOf[K]
+}
+type Stage = StageOf[int]
+
+// PipelineOf is a chain of maps between named categories, such as
+// seed -> soil -> fertilizer, where each stage's source is the destination
+// of the stage before it.
+type PipelineOf[K Integer] struct {
+	stages []StageOf[K]
+}
+type Pipeline = PipelineOf[int]
+
+// Add appends a stage, which must start from the last destination.
+func (p *PipelineOf[K]) Add(source, destination string, rangeMap RangeMapOf[K]) error {
+	if len(p.stages) > 0 && p.stages[len(p.stages)-1].Destination != source {
+		return fmt.Errorf("stage %s-to-%s does not follow %s", source, destination, p.stages[len(p.stages)-1].Destination)
+	}
+	p.stages = append(p.stages, StageOf[K]{source, destination, rangeMap})
+	return nil
+}
+
+func (p PipelineOf[K]) Stages() []StageOf[K] {
+	return p.stages
+}
+
+// Categories returns the names of the categories in order.
+func (p PipelineOf[K]) Categories() []string {
+	if len(p.stages) == 0 {
+		return nil
+	}
+	categories := []string{p.stages[0].Source}
+	for _, stage := range p.stages {
+		categories = append(categories, stage.Destination)
+	}
+	return categories
+}
+
+// path returns the categories from one to another and the maps between them
+// in the order they apply. If from follows to, the maps are inverted.
+func (p PipelineOf[K]) path(from, to string) ([]string, []RangeMapOf[K], error) {
+	categories := p.Categories()
+	start := slices.Index(categories, from)
+	if start < 0 {
+		return nil, nil, fmt.Errorf("%w %q", ErrCategory, from)
+	}
+	end := slices.Index(categories, to)
+	if end < 0 {
+		return nil, nil, fmt.Errorf("%w %q", ErrCategory, to)
+	}
+	if start <= end {
+		maps := make([]RangeMapOf[K], 0, end-start)
+		for _, stage := range p.stages[start:end] {
+			maps = append(maps, stage.Map)
+		}
+		return categories[start : end+1], maps, nil
+	}
+	maps := make([]RangeMapOf[K], 0, start-end)
+	for index := start - 1; index >= end; index-- {
+		inverse, err := p.stages[index].Map.Invert()
+		if err != nil {
+			return nil, nil, fmt.Errorf("stage %s-to-%s: %w", p.stages[index].Source, p.stages[index].Destination, err)
+		}
+		maps = append(maps, inverse)
+	}
+	path := slices.Clone(categories[end : start+1])
+	slices.Reverse(path)
+	return path, maps, nil
+}
+
+// Compose returns the single map from one category to another.
+//
+// Going backward through the pipeline requires each stage to be invertible.
+func (p PipelineOf[K]) Compose(from, to string) (RangeMapOf[K], error) {
+	_, maps, err := p.path(from, to)
+	if err != nil || len(maps) == 0 {
+		return RangeMapOf[K]{}, err
+	}
+	return maps[0].ReduceWith(maps[1:], true), nil
+}
+
+// Map maps a value from one category to another, one stage at a time.
+func (p PipelineOf[K]) Map(from, to string, value K) (K, error) {
+	_, maps, err := p.path(from, to)
+	if err != nil {
+		return 0, err
+	}
+	for _, rangeMap := range maps {
+		value = rangeMap.Map(value)
+	}
+	return value, nil
+}
+
+// TraceStep is the value of a trace in one category.
+type TraceStep[V any] struct {
+	Category string
+	Value    V
+}
+
+// Trace is the value of a point or span in each category along a pipeline.
+type Trace[V any] []TraceStep[V]
+
+func (t Trace[V]) String() string {
+	steps := make([]string, len(t))
+	for index, step := range t {
+		steps[index] = fmt.Sprintf("%s %v", step.Category, step.Value)
+	}
+	return strings.Join(steps, " -> ")
+}
+
+// Trace returns the value in each category from one to another.
+func (p PipelineOf[K]) Trace(from, to string, value K) (Trace[K], error) {
+	categories, maps, err := p.path(from, to)
+	if err != nil {
+		return nil, err
+	}
+	trace := Trace[K]{{categories[0], value}}
+	for index, rangeMap := range maps {
+		value = rangeMap.Map(value)
+		trace = append(trace, TraceStep[K]{categories[index+1], value})
+	}
+	return trace, nil
+}
+
+// TraceSpan returns the image of a span in each category from one to another.
+func (p PipelineOf[K]) TraceSpan(from, to string, span SpanOf[K]) (Trace[SpanSetOf[K]], error) {
+	categories, maps, err := p.path(from, to)
+	if err != nil {
+		return nil, err
+	}
+	set := SpanSetOf[K]{}
+	if !span.Empty() {
+		set.Add(span, struct{}{})
+	}
+	trace := Trace[SpanSetOf[K]]{{categories[0], set}}
+	for index, rangeMap := range maps {
+		set = rangeMap.MapSet(set)
+		trace = append(trace, TraceStep[SpanSetOf[K]]{categories[index+1], set})
+	}
+	return trace, nil
+}
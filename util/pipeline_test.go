@@ -0,0 +1,84 @@
+package util
+
+import (
+	"errors"
+	"testing"
+)
+
+// samplePipeline is the almanac from the day 5 example.
+func samplePipeline(t *testing.T) Pipeline {
+	type stage struct {
+		source, destination string
+		ranges              [][3]int // destination start, source start, length
+	}
+	pipeline := Pipeline{}
+	for _, stage := range []stage{
+		{"seed", "soil", [][3]int{{50, 98, 2}, {52, 50, 48}}},
+		{"soil", "fertilizer", [][3]int{{0, 15, 37}, {37, 52, 2}, {39, 0, 15}}},
+		{"fertilizer", "water", [][3]int{{49, 53, 8}, {0, 11, 42}, {42, 0, 7}, {57, 7, 4}}},
+		{"water", "light", [][3]int{{88, 18, 7}, {18, 25, 70}}},
+		{"light", "temperature", [][3]int{{45, 77, 23}, {81, 45, 19}, {68, 64, 13}}},
+		{"temperature", "humidity", [][3]int{{0, 69, 1}, {1, 0, 69}}},
+		{"humidity", "location", [][3]int{{60, 56, 37}, {56, 93, 4}}},
+	} {
+		rangeMap := RangeMap{}
+		for _, r := range stage.ranges {
+			rangeMap.Add(Span{r[1], r[1] + r[2]}, r[0]-r[1])
+		}
+		if err := pipeline.Add(stage.source, stage.destination, rangeMap); err != nil {
+			t.Fatalf("Add(%s, %s): unexpected error %v", stage.source, stage.destination, err)
+		}
+	}
+	return pipeline
+}
+
+func TestPipeline(t *testing.T) {
+	pipeline := samplePipeline(t)
+	trace, err := pipeline.Trace("seed", "location", 79)
+	expected := "seed 79 -> soil 81 -> fertilizer 81 -> water 81 -> light 74 -> temperature 78 -> humidity 78 -> location 82"
+	if err != nil || trace.String() != expected {
+		t.Errorf("Trace(seed, location, 79): expected %s, got %s, %v", expected, trace, err)
+	}
+
+	type test struct {
+		from, to      string
+		value, mapped int
+	}
+	for _, test := range []test{
+		{"seed", "location", 14, 43},
+		{"soil", "light", 81, 74},
+		{"water", "water", 5, 5},
+		{"location", "seed", 82, 79},
+		{"humidity", "light", 78, 74},
+	} {
+		mapped, err := pipeline.Map(test.from, test.to, test.value)
+		if err != nil || mapped != test.mapped {
+			t.Errorf("Map(%s, %s, %d): expected %d, got %d, %v", test.from, test.to, test.value, test.mapped, mapped, err)
+		}
+		composed, err := pipeline.Compose(test.from, test.to)
+		if err != nil || composed.Map(test.value) != test.mapped {
+			t.Errorf("Compose(%s, %s).Map(%d): expected %d, got %d, %v", test.from, test.to, test.value, test.mapped, composed.Map(test.value), err)
+		}
+	}
+
+	spans, err := pipeline.TraceSpan("seed", "location", Span{79, 93})
+	if err != nil || len(spans) != 8 || spans[7].Value.Min() != 46 || spans[7].Value.Measure() != 14 {
+		t.Errorf("TraceSpan(seed, location, [79,93)): expected minimum 46 over 14 locations, got %s, %v", spans, err)
+	}
+	spans, err = pipeline.TraceSpan("seed", "location", Span{5, 5})
+	if err != nil || len(spans) != 8 {
+		t.Errorf("TraceSpan(seed, location, [5,5)): expected 8 steps, got %s, %v", spans, err)
+	}
+	for _, step := range spans {
+		if err := step.Value.Validate(); err != nil || step.Value.set.len() != 0 {
+			t.Errorf("TraceSpan(seed, location, [5,5)): expected %s to be empty, got %s, %v", step.Category, step.Value, err)
+		}
+	}
+
+	if _, err := pipeline.Map("seed", "weather", 1); !errors.Is(err, ErrCategory) {
+		t.Errorf("Map(seed, weather): expected ErrCategory, got %v", err)
+	}
+	if err := pipeline.Add("soil", "seed", RangeMap{}); err == nil {
+		t.Errorf("Add(soil, seed): expected error after location")
+	}
+}
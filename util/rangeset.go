@@ -1,129 +1,284 @@
 package util
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
-	"sort"
+	"slices"
 )
 
-type Span [2]int
-type spanValue[T any] struct {
-	span  Span
+type spanValue[K Integer, T any] struct {
+	span  SpanOf[K]
 	value T
 }
 
-func (s spanValue[T]) String() string {
+func (s spanValue[K, T]) String() string {
 	return fmt.Sprintf("%s=%v", s.span, s.value)
 }
 
-func (s Span) Contains(value int) bool {
-	return s[0] <= value && value < s[1]
+type RangeSetOf[K Integer, T any] struct {
+	set spanStore[K, T]
 }
 
-func (s Span) Overlaps(t Span) bool {
-	return !(t[1] <= s[0] || t[0] >= s[1])
+type RangeSet[T any] = RangeSetOf[int, T]
+
+// NewRangeSet returns an empty set using the given storage backend.
+//
+// The zero value of a RangeSet is an empty set using SliceBackend. The
+// results of operations such as Cover and Intersect always use SliceBackend.
+func NewRangeSet[K Integer, T any](backend Backend) RangeSetOf[K, T] {
+	return RangeSetOf[K, T]{newSpanStore[K, T](backend)}
 }
 
-func (s Span) Intersect(t Span) Span {
-	result := Span(s)
-	if t[0] > s[0] {
-		result[0] = t[0]
-	}
-	if t[1] < s[1] {
-		result[1] = t[1]
-	}
-	if result[1] < result[0] {
-		result[0] = result[1]
-	}
-	return result
+func (s RangeSetOf[K, T]) Backend() Backend {
+	return s.set.backend()
 }
 
-func (s Span) String() string {
-	return fmt.Sprintf("[%d,%d)", s[0], s[1])
+// Clone returns a copy of the set with the same backend. Values are copied
+// shallowly.
+func (s RangeSetOf[K, T]) Clone() RangeSetOf[K, T] {
+	return RangeSetOf[K, T]{set: s.set.clone()}
 }
 
-type RangeSet[T any] struct {
-	set []spanValue[T]
+func (s *spanStore[K, T]) add(span SpanOf[K], value T) *T {
+	index := s.bisect(span[0])
+	s.replace(index, index, spanValue[K, T]{span, value})
+	s.check()
+	return &s.at(index).value
 }
 
-// bisectRow returns the index of the next gridInfo for a given column
-func bisect[T any](set []spanValue[T], value int) int {
-	return sort.Search(len(set), func(index int) bool {
-		return set[index].span.Contains(value) || set[index].span[0] > value
-	})
+// Add inserts a span into the set without checking for overlaps.
+//
+// See AddWith to control what happens when the span overlaps existing ranges.
+func (s *RangeSetOf[K, T]) Add(span SpanOf[K], value T) *T {
+	return s.set.add(span, value)
+}
+
+// OverlapPolicy selects how AddWith handles a span overlapping existing ranges.
+type OverlapPolicy int
+
+const (
+	// OverlapReject returns ErrOverlap and leaves the set unchanged.
+	OverlapReject OverlapPolicy = iota
+	// OverlapOverwrite replaces the covered part of existing ranges.
+	OverlapOverwrite
+	// OverlapMerge combines values where the span covers existing ranges.
+	OverlapMerge
+)
+
+var ErrOverlap = errors.New("span overlaps existing range")
+
+// overlapping returns the index range of entries which overlap a span.
+func (s *spanStore[K, T]) overlapping(span SpanOf[K]) (int, int) {
+	start := s.bisect(span[0])
+	end := start
+	for info := range s.from(start) {
+		if info.span[0] >= span[1] {
+			break
+		}
+		end++
+	}
+	return start, end
+}
+
+// addWith inserts a span into the set, resolving overlaps by policy.
+//
+// With OverlapMerge, covered parts take combine(existing, value) and
+// uncovered parts take value.
+func (s *spanStore[K, T]) addWith(span SpanOf[K], value T, policy OverlapPolicy, combine CombineFunc[T]) error {
+	if span.Empty() {
+		return nil
+	}
+	start, end := s.overlapping(span)
+	if start == end {
+		s.replace(start, start, spanValue[K, T]{span, value})
+		s.check()
+		return nil
+	}
+	switch policy {
+	case OverlapReject:
+		return fmt.Errorf("%w: %s overlaps %s", ErrOverlap, span, s.at(start).span)
+	case OverlapOverwrite:
+		s.remove(span)
+		s.add(span, value)
+		return nil
+	case OverlapMerge:
+	default:
+		return fmt.Errorf("unknown overlap policy %d", policy)
+	}
+	merged := make([]spanValue[K, T], 0, 2*(end-start)+1)
+	sweep := span[0]
+	for index := start; index < end; index++ {
+		info := s.at(index)
+		if info.span[0] < span[0] {
+			extend(&merged, SpanOf[K]{info.span[0], span[0]}, info.value)
+		} else if sweep < info.span[0] {
+			extend(&merged, SpanOf[K]{sweep, info.span[0]}, value)
+		}
+		sweep = min(info.span[1], span[1])
+		extend(&merged, SpanOf[K]{max(info.span[0], span[0]), sweep}, combine(&info.value, &value))
+		if info.span[1] > span[1] {
+			extend(&merged, SpanOf[K]{span[1], info.span[1]}, info.value)
+		}
+	}
+	if sweep < span[1] {
+		extend(&merged, SpanOf[K]{sweep, span[1]}, value)
+	}
+	s.replace(start, end, merged...)
+	s.check()
+	return nil
 }
 
-func add[T any](set *[]spanValue[T], span Span, value T) *T {
-	index := bisect(*set, span[0])
-	if index == len(*set) {
-		*set = append(*set, spanValue[T]{span, value})
-	} else {
-		*set = append((*set)[:index+1], (*set)[index:]...)
-		(*set)[index] = spanValue[T]{span, value}
+// AddWith inserts a span into the set, resolving overlaps by policy.
+//
+// The combine function is only used by OverlapMerge.
+func (s *RangeSetOf[K, T]) AddWith(span SpanOf[K], value T, policy OverlapPolicy, combine CombineFunc[T]) error {
+	return s.set.addWith(span, value, policy, combine)
+}
+
+// remove deletes a span from the set.
+//
+// Entries entirely within the span are dropped; entries which partially
+// overlap the span are trimmed, or split in two if the span is interior.
+func (s *spanStore[K, T]) remove(span SpanOf[K]) {
+	if span.Empty() {
+		return
+	}
+	start, end := s.overlapping(span)
+	if start == end {
+		return
 	}
-	return &(*set)[index].value
+	first := *s.at(start)
+	last := *s.at(end - 1)
+	trimmed := make([]spanValue[K, T], 0, 2)
+	if before, _ := first.span.Split(span[0]); !before.Empty() {
+		trimmed = append(trimmed, spanValue[K, T]{before, first.value})
+	}
+	if _, after := last.span.Split(span[1]); !after.Empty() {
+		trimmed = append(trimmed, spanValue[K, T]{after, last.value})
+	}
+	s.replace(start, end, trimmed...)
+	s.check()
 }
 
-func (s *RangeSet[T]) Add(span Span, value T) *T {
-	return add(&s.set, span, value)
+// Remove deletes a span from the set, trimming or splitting any entries
+// which partially overlap it.
+func (s *RangeSetOf[K, T]) Remove(span SpanOf[K]) {
+	s.set.remove(span)
 }
 
-func extend[T any](s *[]spanValue[T], span Span, value T) *spanValue[T] {
-	*s = append(*s, spanValue[T]{span, value})
+func extend[K Integer, T any](s *[]spanValue[K, T], span SpanOf[K], value T) *spanValue[K, T] {
+	*s = append(*s, spanValue[K, T]{span, value})
 	return &(*s)[len(*s)-1]
 }
 
-func (s RangeSet[T]) GetRange(key int) *RangeResult[T] {
-	index := bisect(s.set, key)
-	if index < len(s.set) && s.set[index].span.Contains(key) {
-		return &RangeResult[T]{s.set[index].span, &s.set[index].value}
+// extend appends a range which must sort after every range in the set.
+func (s *spanStore[K, T]) extend(span SpanOf[K], value T) {
+	s.replace(s.len(), s.len(), spanValue[K, T]{span, value})
+}
+
+func (s RangeSetOf[K, T]) GetRange(key K) *RangeResultOf[K, T] {
+	index := s.set.bisect(key)
+	if index < s.set.len() && s.set.at(index).span.Contains(key) {
+		info := s.set.at(index)
+		return &RangeResultOf[K, T]{info.span, &info.value}
 	}
 	return nil
 }
 
-func get[T any](set []spanValue[T], key int) *T {
-	index := bisect(set, key)
-	if index < len(set) && set[index].span.Contains(key) {
-		return &set[index].value
+func (s *spanStore[K, T]) get(key K) *T {
+	index := s.bisect(key)
+	if index < s.len() && s.at(index).span.Contains(key) {
+		return &s.at(index).value
 	}
 	return nil
 }
 
-func (s RangeSet[T]) Get(key int) *T {
-	return get(s.set, key)
+func (s RangeSetOf[K, T]) Get(key K) *T {
+	return s.set.get(key)
 }
 
-type RangeResult[T any] struct {
-	Span  Span
+type RangeResultOf[K Integer, T any] struct {
+	Span  SpanOf[K]
 	Value *T
 }
 
-// DoIntersectSet invokes a function on the intersection of a range with the set.
+type RangeResult[T any] = RangeResultOf[int, T]
+
+// Overlapping returns the ranges which overlap a span, in order.
 //
-// If a call returns false, no more ranges are visited.
-func (s RangeSet[T]) DoIntersect(t RangeSet[T], do func(ss, ts, ix Span, svalue, tvalue *T) bool) {
-	if len(s.set) == 0 || len(t.set) == 0 {
-		return
+// If clip is true, the returned spans are clipped to the span.
+func (s RangeSetOf[K, T]) Overlapping(span SpanOf[K], clip bool) []RangeResultOf[K, T] {
+	if span.Empty() {
+		return nil
 	}
-	sIndex := bisect(s.set, t.set[0].span[0])
-	tIndex := 0
-	for sIndex < len(s.set) && tIndex < len(t.set) {
-		sinfo := &s.set[sIndex]
-		tinfo := &t.set[tIndex]
-		if tinfo.span[0] >= s.Max() {
+	start, end := s.set.overlapping(span)
+	results := make([]RangeResultOf[K, T], 0, end-start)
+	for info := range s.set.from(start) {
+		if len(results) == end-start {
 			break
 		}
-		intersection := sinfo.span.Intersect(tinfo.span)
-		if intersection[0] != intersection[1] {
-			if !do(sinfo.span, tinfo.span, intersection, &sinfo.value, &tinfo.value) {
-				break
-			}
-		}
-		if tinfo.span[1] > sinfo.span[1] {
-			sIndex++
-		} else {
-			tIndex++
+		result := RangeResultOf[K, T]{info.span, &info.value}
+		if clip {
+			result.Span = result.Span.Intersect(span)
 		}
+		results = append(results, result)
 	}
+	return results
+}
+
+// Floor returns the range containing key or the last range before it.
+func (s RangeSetOf[K, T]) Floor(key K) *RangeResultOf[K, T] {
+	index := s.set.bisect(key)
+	if index == s.set.len() || !s.set.at(index).span.Contains(key) {
+		index--
+	}
+	if index < 0 {
+		return nil
+	}
+	info := s.set.at(index)
+	return &RangeResultOf[K, T]{info.span, &info.value}
+}
+
+// Ceiling returns the range containing key or the first range after it.
+func (s RangeSetOf[K, T]) Ceiling(key K) *RangeResultOf[K, T] {
+	index := s.set.bisect(key)
+	if index == s.set.len() {
+		return nil
+	}
+	info := s.set.at(index)
+	return &RangeResultOf[K, T]{info.span, &info.value}
+}
+
+// Measure returns the total length of the ranges in the set.
+func (s RangeSetOf[K, T]) Measure() K {
+	var measure K
+	for info := range s.set.from(0) {
+		measure += info.span.Len()
+	}
+	return measure
+}
+
+// DoIntersect invokes a function on each intersection of a range of s with
+// a range of t, with both ranges and their values.
+//
+// If a call returns false, no more ranges are visited.
+func (s RangeSetOf[K, T]) DoIntersect(t RangeSetOf[K, T], do func(ss, ts, ix SpanOf[K], svalue, tvalue *T) bool) {
+	if s.set.len() == 0 || t.set.len() == 0 {
+		return
+	}
+	// Intersections lie between the later first start and the earlier last
+	// end, so the sweep skips ahead to one and stops at the other.
+	from, to := max(s.Min(), t.Min()), min(s.Max(), t.Max())
+	sweepPair(s, t, from, func(span SpanOf[K], sInfo, tInfo *spanValue[K, T]) bool {
+		if span[0] >= to {
+			return false
+		}
+		if sInfo == nil || tInfo == nil {
+			return true
+		}
+		return do(sInfo.span, tInfo.span, span, &sInfo.value, &tInfo.value)
+	})
 }
 
 type CombineFunc[T any] func(svalue, tvalue *T) T
@@ -139,270 +294,428 @@ type CombineFunc[T any] func(svalue, tvalue *T) T
 //	           ______
 //	                ______
 //	                      ______
-func (s RangeSet[T]) DoCover(t RangeSet[T], combine CombineFunc[T], visit func(Span, T) bool) {
-	if len(s.set) == 0 {
-		return
-	}
-	if len(t.set) == 0 {
-		return
-	}
-	sIndex := 0
-	tIndex := 0
-	sweep := min(s.set[0].span[0], t.set[0].span[0])
-
-	// sweep line algorithm:
-	//   S = sweep line
-	//   L = first = span with least lower bound
-	//   R = second = span with greatest lower bound
-	// min{S.max, T.max} when comparing spans S (from s.set) and T (from t.set).
-	for sIndex < len(s.set) && tIndex < len(t.set) {
-		first := &s.set[sIndex]
-		second := &t.set[tIndex]
-		if second.span[0] < first.span[0] {
-			second, first = first, second
+func (s RangeSetOf[K, T]) DoCover(t RangeSetOf[K, T], combine CombineFunc[T], visit func(SpanOf[K], T) bool) {
+	sweepPair(s, t, sweepStart(s, t), func(span SpanOf[K], sInfo, tInfo *spanValue[K, T]) bool {
+		switch {
+		case tInfo == nil:
+			return visit(span, sInfo.value)
+		case sInfo == nil:
+			return visit(span, tInfo.value)
 		}
+		return visit(span, combine(&sInfo.value, &tInfo.value))
+	})
+}
 
-		// skip gaps
-		if sweep < first.span[0] {
-			sweep = first.span[0]
-		}
+func (s RangeSetOf[K, T]) Cover(t RangeSetOf[K, T], combine CombineFunc[T]) RangeSetOf[K, T] {
+	cover := RangeSetOf[K, T]{}
+	s.DoCover(t, combine, func(s SpanOf[K], value T) bool {
+		cover.set.extend(s, value)
+		return true
+	})
+	cover.set.check()
+	return cover
+}
 
-		// check intersection
-		if second.span[0] < first.span[1] {
-			// add [S,Rl] if not empty
-			if sweep != second.span[0] {
-				visit(Span{sweep, second.span[0]}, first.value)
-			}
-			// add [Rl, min{Lr,Rr}]; advance span with min{Lr,Rr}
-			sweep = first.span[1]
-			if second.span[1] < sweep {
-				sweep = second.span[1]
-			}
-			visit(Span{second.span[0], sweep}, combine(&first.value, &second.value))
-		} else {
-			// no intersection: add [S,Lr]
-			visit(Span{sweep, first.span[1]}, first.value)
-			sweep = first.span[1]
-		}
-		// advance span with min{Lr,Rr}
-		if s.set[sIndex].span[1] < t.set[tIndex].span[1] {
-			sIndex++
-		} else {
-			tIndex++
-		}
-	}
-	for _, set := range [][]spanValue[T]{s.set[sIndex:], t.set[tIndex:]} {
-		if len(set) == 0 {
-			continue
+// IntersectSet intersects two sets and returns a new set with all intersecting regions.
+func (s RangeSetOf[K, T]) Intersect(t RangeSetOf[K, T], combine CombineFunc[T]) RangeSetOf[K, T] {
+	result := RangeSetOf[K, T]{}
+	s.DoIntersect(t, func(ss, ts, xs SpanOf[K], svalue, tvalue *T) bool {
+		result.set.extend(xs, combine(svalue, tvalue))
+		return true
+	})
+	result.set.check()
+	return result
+}
+
+// DoDifference invokes a function on each range of s which is not covered by t.
+//
+// If a call returns false, no more ranges are visited.
+func (s RangeSetOf[K, T]) DoDifference(t RangeSetOf[K, T], visit func(SpanOf[K], *T) bool) {
+	sweepPick(s, t, func(sInfo, tInfo *spanValue[K, T]) int {
+		if tInfo != nil {
+			return -1
 		}
-		info := &set[0]
-		// skip gap
-		if sweep <= info.span[0] {
-			sweep = info.span[0]
+		return 0
+	}, visit)
+}
+
+// Difference returns the ranges of s which are not covered by t.
+func (s RangeSetOf[K, T]) Difference(t RangeSetOf[K, T]) RangeSetOf[K, T] {
+	result := RangeSetOf[K, T]{}
+	s.DoDifference(t, func(span SpanOf[K], value *T) bool {
+		result.set.extend(span, *value)
+		return true
+	})
+	result.set.check()
+	return result
+}
+
+// DoUnion invokes a function on each range in the union of two sets.
+//
+// Where the sets overlap the ranges from s take precedence; see DoCover to
+// combine the overlapping values instead.
+//
+// If a call returns false, no more ranges are visited.
+func (s RangeSetOf[K, T]) DoUnion(t RangeSetOf[K, T], visit func(SpanOf[K], *T) bool) {
+	sweepPick(s, t, func(sInfo, tInfo *spanValue[K, T]) int {
+		if sInfo != nil {
+			return 0
 		}
-		visit(Span{sweep, info.span[1]}, info.value)
-		for index := 1; index < len(set); index++ {
-			visit(set[index].span, set[index].value)
+		return 1
+	}, visit)
+}
+
+// Union returns the union of two sets, preferring values from s where they overlap.
+func (s RangeSetOf[K, T]) Union(t RangeSetOf[K, T]) RangeSetOf[K, T] {
+	result := RangeSetOf[K, T]{}
+	s.DoUnion(t, func(span SpanOf[K], value *T) bool {
+		result.set.extend(span, *value)
+		return true
+	})
+	result.set.check()
+	return result
+}
+
+// DoSymmetricDifference invokes a function on each range covered by exactly
+// one of the two sets.
+//
+// If a call returns false, no more ranges are visited.
+func (s RangeSetOf[K, T]) DoSymmetricDifference(t RangeSetOf[K, T], visit func(SpanOf[K], *T) bool) {
+	sweepPick(s, t, func(sInfo, tInfo *spanValue[K, T]) int {
+		switch {
+		case tInfo == nil:
+			return 0
+		case sInfo == nil:
+			return 1
 		}
-	}
+		return -1
+	}, visit)
 }
 
-func (s RangeSet[T]) Cover(t RangeSet[T], combine CombineFunc[T]) RangeSet[T] {
-	cover := RangeSet[T]{}
-	s.DoCover(t, combine, func(s Span, value T) bool {
-		extend(&cover.set, s, value)
+// SymmetricDifference returns the ranges covered by exactly one of the two sets.
+func (s RangeSetOf[K, T]) SymmetricDifference(t RangeSetOf[K, T]) RangeSetOf[K, T] {
+	result := RangeSetOf[K, T]{}
+	s.DoSymmetricDifference(t, func(span SpanOf[K], value *T) bool {
+		result.set.extend(span, *value)
 		return true
 	})
-	return cover
+	result.set.check()
+	return result
 }
 
-// IntersectSet intersects two sets and returns a new set with all intersecting regions.
-func (s RangeSet[T]) Intersect(t RangeSet[T], combine CombineFunc[T]) RangeSet[T] {
-	result := RangeSet[T]{}
-	s.DoIntersect(t, func(ss, ts, xs Span, svalue, tvalue *T) bool {
-		result.set = append(result.set, spanValue[T]{xs, combine(svalue, tvalue)})
+// DoComplement invokes a function on each gap in the set within bounds.
+//
+// If a call returns false, no more gaps are visited.
+func (s RangeSetOf[K, T]) DoComplement(bounds SpanOf[K], visit func(SpanOf[K]) bool) {
+	sweep := bounds[0]
+	for index := s.set.bisect(bounds[0]); index < s.set.len() && sweep < bounds[1]; index++ {
+		span := s.set.at(index).span
+		if sweep < span[0] && !visit(SpanOf[K]{sweep, min(span[0], bounds[1])}) {
+			return
+		}
+		sweep = max(sweep, span[1])
+	}
+	if sweep < bounds[1] {
+		visit(SpanOf[K]{sweep, bounds[1]})
+	}
+}
+
+// Complement returns the gaps in the set within bounds, each with the given value.
+func (s RangeSetOf[K, T]) Complement(bounds SpanOf[K], value T) RangeSetOf[K, T] {
+	result := RangeSetOf[K, T]{}
+	s.DoComplement(bounds, func(span SpanOf[K]) bool {
+		result.set.extend(span, value)
 		return true
 	})
+	result.set.check()
 	return result
 }
 
+// Normalize returns the set with adjacent ranges of equal value coalesced.
+func (s RangeSetOf[K, T]) Normalize(equal func(x, y *T) bool) RangeSetOf[K, T] {
+	result := RangeSetOf[K, T]{}
+	var last *spanValue[K, T]
+	for info := range s.set.from(0) {
+		if last != nil && last.span[1] == info.span[0] && equal(&last.value, &info.value) {
+			last.span[1] = info.span[1]
+		} else {
+			last = extend(&result.set.items, info.span, info.value)
+		}
+	}
+	result.set.check()
+	return result
+}
+
+// Coalesce returns the set with adjacent ranges of equal value coalesced.
+func Coalesce[K Integer, T comparable](s RangeSetOf[K, T]) RangeSetOf[K, T] {
+	return s.Normalize(func(x, y *T) bool {
+		return *x == *y
+	})
+}
+
 // Do invokes a function on all ranges in the set.
 //
 // If a call returns false, no more ranges are visited.
-func (s RangeSet[T]) Do(do func(Span, *T) bool) {
-	for _, spanInfo := range s.set {
+func (s RangeSetOf[K, T]) Do(do func(SpanOf[K], *T) bool) {
+	for spanInfo := range s.set.from(0) {
 		if !do(spanInfo.span, &spanInfo.value) {
 			break
 		}
 	}
 }
 
-func (s RangeSet[T]) Min() int {
-	if len(s.set) == 0 {
+func (s RangeSetOf[K, T]) Min() K {
+	if s.set.len() == 0 {
 		return 0
 	}
-	return s.set[0].span[0]
+	return s.set.at(0).span[0]
 }
 
-func (s RangeSet[T]) MinValue() *T {
-	if len(s.set) == 0 {
+func (s RangeSetOf[K, T]) MinValue() *T {
+	if s.set.len() == 0 {
 		return nil
 	}
-	return &s.set[0].value
+	return &s.set.at(0).value
 }
 
-func (s RangeSet[T]) Max() int {
-	if len(s.set) == 0 {
+func (s RangeSetOf[K, T]) Max() K {
+	if s.set.len() == 0 {
 		return 0
 	}
-	return s.set[len(s.set)-1].span[1]
+	return s.set.at(s.set.len() - 1).span[1]
 }
 
-func (s RangeSet[T]) MaxValue() *T {
-	if len(s.set) == 0 {
+func (s RangeSetOf[K, T]) MaxValue() *T {
+	if s.set.len() == 0 {
 		return nil
 	}
-	return &s.set[len(s.set)-1].value
+	return &s.set.at(s.set.len() - 1).value
 }
 
-func (s RangeSet[T]) Span() Span {
-	return Span{s.Min(), s.Max()}
+func (s RangeSetOf[K, T]) Span() SpanOf[K] {
+	return SpanOf[K]{s.Min(), s.Max()}
 }
 
-func (s RangeSet[T]) String() string {
+func (s RangeSetOf[K, T]) String() string {
 	str := "{"
-	for index, spanValue := range s.set {
+	for index := range s.set.len() {
+		spanValue := s.set.at(index)
 		str += fmt.Sprintf(" [%d]=%s", index, spanValue)
 	}
 	str += " }"
 	return str
 }
 
-type RangeMap RangeSet[int]
+// SpanSet is a set of ranges without values.
+type SpanSetOf[K Integer] = RangeSetOf[K, struct{}]
+type SpanSet = SpanSetOf[int]
 
-// Combine range-maps.
-func (s RangeMap) CombineMap(t RangeMap, mergeUnmapped bool) RangeMap {
-	result := RangeMap{}
-	if len(s.set) == 0 {
-		return t
-	}
-	if len(t.set) == 0 {
-		return s
-	}
-	sweep := RangeSet[int](s).Min()
-	if tMin := RangeSet[int](t).Min(); mergeUnmapped && tMin < sweep {
-		sweep = tMin
-	}
-	sIndex := 0
-	tIndex := 0
-	for sIndex < len(s.set) {
-		sspan := s.set[sIndex].span
-		if sweep > sspan[0] {
-			sspan[0] = sweep
+// coalesce returns a set of the given spans, merging any which overlap or touch.
+func coalesce[K Integer](spans []SpanOf[K]) SpanSetOf[K] {
+	slices.SortFunc(spans, func(a, b SpanOf[K]) int {
+		return cmp.Compare(a[0], b[0])
+	})
+	result := SpanSetOf[K]{}
+	for _, span := range spans {
+		if span.Empty() {
+			continue
 		}
-		var tspan Span
-		if mergeUnmapped && tIndex < len(t.set) {
-			tspan = t.set[tIndex].span
-			if sweep > tspan[0] {
-				tspan[0] = sweep
-			}
-
-			if tspan[0] < sspan[0] {
-				sweep = Min(tspan[1], sspan[0])
-				extend(&result.set, Span{tspan[0], sweep}, t.set[tIndex].value)
+		if last := result.set.len() - 1; last >= 0 {
+			if union, ok := result.set.at(last).span.Union(span); ok {
+				result.set.at(last).span = union
+				continue
 			}
 		}
+		result.set.extend(span, struct{}{})
+	}
+	result.set.check()
+	return result
+}
 
-		// Map contiguous subset of domain
-		if sspan.Contains(sweep) {
-			svalue := s.set[sIndex].value
-			mapped := Span{sweep + svalue, svalue + sspan[1]}
-			tMapIndex := bisect(t.set, mapped[0])
-			if tMapIndex != len(t.set) {
-				tinfo := &t.set[tMapIndex]
-				value := svalue + tinfo.value
-				if mapped[0] < tinfo.span[0] {
-					if tinfo.span[0] < mapped[1] {
-						extend(&result.set, Span{sweep, sweep + tinfo.span[0] - mapped[0]}, svalue)
-						sweep += tinfo.span[0] - mapped[0]
-						mapped[0] = tinfo.span[0]
-					} else {
-						value = svalue
-					}
-				} else if tinfo.span[1] < mapped[1] {
-					mapped[1] = tinfo.span[1]
-				}
-				delta := mapped[1] - mapped[0]
-				extend(&result.set, Span{sweep, sweep + delta}, value)
-				sweep += delta
-			} else {
-				extend(&result.set, Span{sweep, sspan[1]}, svalue)
-				sweep = sspan[1]
-			}
-		} else if !mergeUnmapped {
-			if sweep < sspan[0] {
-				sweep = sspan[0]
-			} else {
-				sweep = sspan[1]
-			}
-		}
+// RangeMapOf maps each span by adding its value to points within it.
+//
+// Unmapped points map to themselves. For unsigned K the arithmetic wraps, so
+// a delta of -d is stored as the two's complement of d.
+type RangeMapOf[K Integer] RangeSetOf[K, K]
+type RangeMap = RangeMapOf[int]
 
-		if sweep >= sspan[1] {
-			sIndex++
-		}
-		if mergeUnmapped && sweep >= tspan[1] {
-			tIndex++
+// CombineMap returns the map which applies s and then t.
+//
+// If mergeUnmapped is false, the result only maps the domain of s.
+func (s RangeMapOf[K]) CombineMap(t RangeMapOf[K], mergeUnmapped bool) RangeMapOf[K] {
+	if t.set.len() == 0 {
+		return s
+	}
+	if s.set.len() == 0 {
+		if mergeUnmapped {
+			return t
 		}
+		return RangeMapOf[K]{}
 	}
-	for ; sIndex < len(s.set); sIndex++ {
-		extend(&result.set, s.set[sIndex].span, s.set[sIndex].value)
+	// Pull t back through s: sweep the image of each range of s against t,
+	// splitting the range where its image meets the ranges of t.
+	pullback := RangeSetOf[K, K]{}
+	image := RangeSetOf[K, K]{}
+	for info := range s.set.from(0) {
+		image.set.items = append(image.set.items[:0], spanValue[K, K]{info.span.Shift(info.value), info.value})
+		if image.set.items[0].span.Empty() {
+			continue
+		}
+		sweepPair(image, RangeSetOf[K, K](t), image.Min(), func(part SpanOf[K], sInfo, tInfo *spanValue[K, K]) bool {
+			if sInfo == nil {
+				return false
+			}
+			delta := sInfo.value
+			if tInfo != nil {
+				delta += tInfo.value
+			}
+			extend(&pullback.set.items, part.Shift(-sInfo.value), delta)
+			return true
+		})
 	}
 	if mergeUnmapped {
-		for ; tIndex < len(t.set); tIndex++ {
-			extend(&result.set, t.set[tIndex].span, t.set[tIndex].value)
-		}
+		// Outside the domain of s only t applies.
+		pullback = pullback.Union(RangeSetOf[K, K](t))
 	}
-	return result
+	pullback.set.check()
+	return RangeMapOf[K](pullback)
 }
 
-func (s *RangeMap) Add(span Span, value int) {
-	add(&s.set, span, value)
+func (s *RangeMapOf[K]) Add(span SpanOf[K], value K) {
+	s.set.add(span, value)
 }
 
-func (s RangeMap) Reduce(maps []RangeMap) RangeMap {
+// AddWith inserts a span into the map, resolving overlaps by policy.
+func (s *RangeMapOf[K]) AddWith(span SpanOf[K], value K, policy OverlapPolicy, combine CombineFunc[K]) error {
+	return s.set.addWith(span, value, policy, combine)
+}
+
+// Remove deletes a span from the map, so that its points map to themselves.
+func (s *RangeMapOf[K]) Remove(span SpanOf[K]) {
+	s.set.remove(span)
+}
+
+func (s RangeMapOf[K]) Reduce(maps []RangeMapOf[K]) RangeMapOf[K] {
+	return s.ReduceWith(maps, false)
+}
+
+// ReduceWith combines a chain of maps, optionally normalizing the result
+// after each step to keep the intermediate maps small.
+func (s RangeMapOf[K]) ReduceWith(maps []RangeMapOf[K], normalize bool) RangeMapOf[K] {
 	result := s
 	for _, rangeMap := range maps {
 		result = result.CombineMap(rangeMap, true)
+		if normalize {
+			result = result.Normalize()
+		}
 	}
 	return result
 }
 
-func (s RangeMap) Map(value int) int {
-	index := bisect(s.set, value)
-	if index == len(s.set) || value < s.set[index].span[0] {
+// Normalize returns the map with adjacent ranges of equal delta coalesced.
+func (s RangeMapOf[K]) Normalize() RangeMapOf[K] {
+	return RangeMapOf[K](Coalesce(RangeSetOf[K, K](s)))
+}
+
+var ErrNotInvertible = errors.New("range map is not invertible")
+
+// Invert returns the inverse of the map.
+//
+// The map is invertible only if it is a bijection: the images of its ranges
+// must not overlap each other, nor any point which is unmapped (and so maps
+// to itself). Points outside the images map to themselves in the inverse.
+func (s RangeMapOf[K]) Invert() (RangeMapOf[K], error) {
+	inverse := RangeMapOf[K]{}
+	for info := range s.set.from(0) {
+		image := info.span.Shift(info.value)
+		if err := inverse.AddWith(image, -info.value, OverlapReject, nil); err != nil {
+			return RangeMapOf[K]{}, fmt.Errorf("%w: %w", ErrNotInvertible, err)
+		}
+	}
+	var collision *SpanOf[K]
+	RangeSetOf[K, K](inverse).DoDifference(RangeSetOf[K, K](s), func(span SpanOf[K], _ *K) bool {
+		collision = &span
+		return false
+	})
+	if collision != nil {
+		return RangeMapOf[K]{}, fmt.Errorf("%w: %s is the image of mapped and unmapped points", ErrNotInvertible, *collision)
+	}
+	return inverse, nil
+}
+
+func (s RangeMapOf[K]) Map(value K) K {
+	index := s.set.bisect(value)
+	if index == s.set.len() || value < s.set.at(index).span[0] {
 		return value
 	}
-	return value + s.set[index].value
+	return value + s.set.at(index).value
+}
+
+// doSpan visits the parts of a span in order with their deltas, which are
+// zero for unmapped parts.
+func (s RangeMapOf[K]) doSpan(span SpanOf[K], visit func(SpanOf[K], K)) {
+	if span.Empty() {
+		return
+	}
+	domain := RangeSetOf[K, K]{set: spanStore[K, K]{items: []spanValue[K, K]{{span: span}}}}
+	sweepPair(domain, RangeSetOf[K, K](s), span[0], func(part SpanOf[K], sInfo, tInfo *spanValue[K, K]) bool {
+		if sInfo == nil {
+			return false
+		}
+		if tInfo == nil {
+			visit(part, 0)
+		} else {
+			visit(part, tInfo.value)
+		}
+		return true
+	})
+}
+
+// mapSpan appends the images of the parts of a span to images.
+func (s RangeMapOf[K]) mapSpan(span SpanOf[K], images []SpanOf[K]) []SpanOf[K] {
+	s.doSpan(span, func(part SpanOf[K], delta K) {
+		images = append(images, part.Shift(delta))
+	})
+	return images
+}
+
+// MapSpan returns the image of a span through the map.
+//
+// Unmapped parts of the span map to themselves.
+func (s RangeMapOf[K]) MapSpan(span SpanOf[K]) SpanSetOf[K] {
+	return coalesce(s.mapSpan(span, nil))
+}
+
+// MapSet returns the image of a set of spans through the map.
+//
+// Unmapped parts of the set map to themselves.
+func (s RangeMapOf[K]) MapSet(set SpanSetOf[K]) SpanSetOf[K] {
+	images := make([]SpanOf[K], 0, set.set.len())
+	for info := range set.set.from(0) {
+		images = s.mapSpan(info.span, images)
+	}
+	return coalesce(images)
 }
 
-func (s RangeMap) Maps(value int) bool {
-	index := bisect(s.set, value)
-	if index == len(s.set) || value < s.set[index].span[0] {
+func (s RangeMapOf[K]) Maps(value K) bool {
+	index := s.set.bisect(value)
+	if index == s.set.len() || value < s.set.at(index).span[0] {
 		return false
 	}
 	return true
 }
 
-func (s RangeMap) String() string {
+func (s RangeMapOf[K]) String() string {
 	str := "{"
-	for index, spanValue := range s.set {
+	for index := range s.set.len() {
+		spanValue := s.set.at(index)
 		str += fmt.Sprintf(" [%d]=%s%+d=>%s", index, spanValue.span, spanValue.value,
-			Span{spanValue.span[0] + spanValue.value, spanValue.span[1] + spanValue.value})
+			spanValue.span.Shift(spanValue.value))
 	}
 	str += " }"
 	return str
 }
 
-func (s RangeMap) Count() int {
-	return len(s.set)
+func (s RangeMapOf[K]) Count() int {
+	return s.set.len()
 }
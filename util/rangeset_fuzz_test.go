@@ -0,0 +1,283 @@
+package util
+
+import (
+	"testing"
+)
+
+// The fuzz targets below decode random bytes into small range sets and check
+// the operations on them against a brute-force model which stores a value
+// for every point.
+
+const modelLo, modelHi = -64, 192
+
+// decodeRangeSet builds a range set from data, consuming three bytes per
+// range: the gap before it, its length and its value.
+func decodeRangeSet(data []byte) (RangeSet[int], []byte) {
+	set := RangeSet[int]{}
+	if len(data) == 0 {
+		return set, data
+	}
+	count := int(data[0] % 8)
+	data = data[1:]
+	if len(data) == 0 {
+		return set, data
+	}
+	sweep := int(int8(data[0])) / 4
+	for ; count > 0 && len(data) >= 3; count-- {
+		start := sweep + int(data[0]%6)
+		end := start + 1 + int(data[1]%12)
+		set.set.extend(Span{start, end}, int(int8(data[2])))
+		sweep = end
+		data = data[3:]
+	}
+	return set, data
+}
+
+func decodeRangeSets(data []byte, count int) []RangeSet[int] {
+	sets := make([]RangeSet[int], count)
+	for index := range sets {
+		sets[index], data = decodeRangeSet(data)
+	}
+	return sets
+}
+
+// pointModel is the brute-force model of a range set.
+type pointModel map[int]int
+
+func modelOf(set RangeSet[int]) pointModel {
+	model := make(pointModel)
+	set.Do(func(span Span, value *int) bool {
+		for point := span[0]; point < span[1]; point++ {
+			model[point] = *value
+		}
+		return true
+	})
+	return model
+}
+
+func (m pointModel) Map(point int) int {
+	if delta, ok := m[point]; ok {
+		return point + delta
+	}
+	return point
+}
+
+func checkModel(t *testing.T, name string, set RangeSet[int], expected pointModel) {
+	t.Helper()
+	if err := set.Validate(); err != nil {
+		t.Fatalf("%s: %v: %s", name, err, set)
+	}
+	got := modelOf(set)
+	for point := modelLo; point < modelHi; point++ {
+		value, ok := got[point]
+		expectedValue, expectedOk := expected[point]
+		if ok != expectedOk || value != expectedValue {
+			t.Fatalf("%s at %d: expected %d (%t), got %d (%t): %s", name, point, expectedValue, expectedOk, value, ok, set)
+		}
+	}
+}
+
+// combine is deliberately not commutative, to check argument order.
+func combine(svalue, tvalue *int) int {
+	return 1000*(*svalue) + *tvalue
+}
+
+var fuzzSeeds = [][]byte{
+	{},
+	{3, 0, 0, 4, 1, 2, 9, 2, 3, 5, 3, 1, 10, 7, 40, 2, 11, 1},
+	{5, 200, 1, 1, 1, 0, 7, 2, 3, 8, 3, 2, 2, 4, 3, 10, 5, 5, 0, 3, 0, 2, 250, 4, 6},
+	{2, 10, 3, 5, 200, 4, 11, 50, 4, 3, 12, 0, 2, 3, 90, 1, 5, 7, 2, 6, 6, 6},
+	{7, 255, 5, 11, 1, 5, 11, 2, 5, 11, 3, 5, 11, 4, 7, 0, 5, 11, 9, 5, 11, 8, 0, 0, 0},
+}
+
+func FuzzIntersect(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sets := decodeRangeSets(data, 2)
+		s, u := sets[0], sets[1]
+		sModel, uModel := modelOf(s), modelOf(u)
+		expected := make(pointModel)
+		for point, svalue := range sModel {
+			if uvalue, ok := uModel[point]; ok {
+				expected[point] = combine(&svalue, &uvalue)
+			}
+		}
+		checkModel(t, "Intersect", s.Intersect(u, combine), expected)
+	})
+}
+
+func FuzzCover(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sets := decodeRangeSets(data, 2)
+		s, u := sets[0], sets[1]
+		sModel, uModel := modelOf(s), modelOf(u)
+		expected := make(pointModel)
+		for point, uvalue := range uModel {
+			expected[point] = uvalue
+		}
+		for point, svalue := range sModel {
+			if uvalue, ok := uModel[point]; ok {
+				expected[point] = combine(&svalue, &uvalue)
+			} else {
+				expected[point] = svalue
+			}
+		}
+		checkModel(t, "Cover", s.Cover(u, combine), expected)
+	})
+}
+
+func FuzzCombineMap(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+	f.Fuzz(func(t *testing.T, data []byte, mergeUnmapped bool) {
+		sets := decodeRangeSets(data, 2)
+		s, u := RangeMap(sets[0]), RangeMap(sets[1])
+		sModel, uModel := modelOf(sets[0]), modelOf(sets[1])
+		combined := s.CombineMap(u, mergeUnmapped)
+		if err := combined.Validate(); err != nil {
+			t.Fatalf("CombineMap(%t): %v: %s", mergeUnmapped, err, combined)
+		}
+		for point := modelLo; point < modelHi; point++ {
+			_, sMaps := sModel[point]
+			_, uMaps := uModel[point]
+			expected := point
+			if sMaps || (mergeUnmapped && uMaps) {
+				expected = uModel.Map(sModel.Map(point))
+			}
+			if got := combined.Map(point); got != expected {
+				t.Fatalf("%s.CombineMap(%s, %t).Map(%d): expected %d, got %d: %s",
+					s, u, mergeUnmapped, point, expected, got, combined)
+			}
+		}
+	})
+}
+
+func FuzzReduce(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+	f.Fuzz(func(t *testing.T, data []byte, normalize bool) {
+		sets := decodeRangeSets(data, 4)
+		maps := make([]RangeMap, len(sets))
+		models := make([]pointModel, len(sets))
+		for index, set := range sets {
+			maps[index] = RangeMap(set)
+			models[index] = modelOf(set)
+		}
+		reduced := maps[0].ReduceWith(maps[1:], normalize)
+		if err := reduced.Validate(); err != nil {
+			t.Fatalf("ReduceWith(%t): %v: %s", normalize, err, reduced)
+		}
+		for point := modelLo; point < modelHi; point++ {
+			expected := point
+			for _, model := range models {
+				expected = model.Map(expected)
+			}
+			if got := reduced.Map(point); got != expected {
+				t.Fatalf("ReduceWith(%v, %t).Map(%d): expected %d, got %d: %s", maps, normalize, point, expected, got, reduced)
+			}
+		}
+	})
+}
+
+func FuzzMap(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		set, _ := decodeRangeSet(data)
+		rangeMap := RangeMap(set)
+		model := modelOf(set)
+		for point := modelLo; point < modelHi; point++ {
+			_, ok := model[point]
+			if got := rangeMap.Map(point); got != model.Map(point) || rangeMap.Maps(point) != ok {
+				t.Fatalf("%s.Map(%d): expected %d (%t), got %d (%t)", rangeMap, point, model.Map(point), ok, got, rangeMap.Maps(point))
+			}
+		}
+		image := rangeMap.MapSpan(Span{modelLo, modelHi})
+		for point := modelLo; point < modelHi; point++ {
+			if image.Get(model.Map(point)) == nil {
+				t.Fatalf("%s.MapSpan: image %s does not contain %d", rangeMap, image, model.Map(point))
+			}
+		}
+	})
+}
+
+func FuzzCoverAll(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sets := decodeRangeSets(data, 4)
+		models := make([]pointModel, len(sets))
+		for index, set := range sets {
+			models[index] = modelOf(set)
+		}
+		// sum weights each set's value by its index, to check the order.
+		sum := func(values []*int) int {
+			total := 0
+			for index, value := range values {
+				if value != nil {
+					total += (index + 1) * 1000 * (*value + 200)
+				}
+			}
+			return total
+		}
+		cover := make(pointModel)
+		intersect := make(pointModel)
+		for point := modelLo; point < modelHi; point++ {
+			values := make([]*int, len(sets))
+			count := 0
+			for index, model := range models {
+				if value, ok := model[point]; ok {
+					values[index] = &value
+					count++
+				}
+			}
+			if count > 0 {
+				cover[point] = sum(values)
+			}
+			if count == len(sets) {
+				intersect[point] = sum(values)
+			}
+		}
+		checkModel(t, "CoverAll", CoverAll(sets, sum), cover)
+		checkModel(t, "IntersectAll", IntersectAll(sets, sum), intersect)
+	})
+}
+
+func FuzzSetAlgebra(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sets := decodeRangeSets(data, 2)
+		s, u := sets[0], sets[1]
+		sModel, uModel := modelOf(s), modelOf(u)
+		union, difference, symmetric := make(pointModel), make(pointModel), make(pointModel)
+		for point, uvalue := range uModel {
+			union[point] = uvalue
+			if _, ok := sModel[point]; !ok {
+				symmetric[point] = uvalue
+			}
+		}
+		for point, svalue := range sModel {
+			union[point] = svalue
+			if _, ok := uModel[point]; !ok {
+				difference[point] = svalue
+				symmetric[point] = svalue
+			}
+		}
+		checkModel(t, "Union", s.Union(u), union)
+		checkModel(t, "Difference", s.Difference(u), difference)
+		checkModel(t, "SymmetricDifference", s.SymmetricDifference(u), symmetric)
+	})
+}
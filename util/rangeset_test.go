@@ -0,0 +1,381 @@
+package util
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func makeRangeSet(entries ...spanValue[int, int]) RangeSet[int] {
+	return makeRangeSetWith(SliceBackend, entries...)
+}
+
+func makeRangeSetWith(backend Backend, entries ...spanValue[int, int]) RangeSet[int] {
+	set := NewRangeSet[int, int](backend)
+	for _, entry := range entries {
+		set.Add(entry.span, entry.value)
+	}
+	return set
+}
+
+func TestRemove(t *testing.T) {
+	type test struct {
+		remove   Span
+		expected []spanValue[int, int]
+	}
+	base := func(backend Backend) RangeSet[int] {
+		return makeRangeSetWith(backend,
+			spanValue[int, int]{Span{0, 5}, 1},
+			spanValue[int, int]{Span{5, 10}, 2},
+			spanValue[int, int]{Span{20, 30}, 3},
+		)
+	}
+	for _, test := range []test{
+		{Span{10, 20}, []spanValue[int, int]{{Span{0, 5}, 1}, {Span{5, 10}, 2}, {Span{20, 30}, 3}}},
+		{Span{7, 7}, []spanValue[int, int]{{Span{0, 5}, 1}, {Span{5, 10}, 2}, {Span{20, 30}, 3}}},
+		{Span{5, 10}, []spanValue[int, int]{{Span{0, 5}, 1}, {Span{20, 30}, 3}}},
+		{Span{3, 7}, []spanValue[int, int]{{Span{0, 3}, 1}, {Span{7, 10}, 2}, {Span{20, 30}, 3}}},
+		{Span{22, 25}, []spanValue[int, int]{{Span{0, 5}, 1}, {Span{5, 10}, 2}, {Span{20, 22}, 3}, {Span{25, 30}, 3}}},
+		{Span{-10, 25}, []spanValue[int, int]{{Span{25, 30}, 3}}},
+		{Span{-10, 40}, []spanValue[int, int]{}},
+	} {
+		for _, backend := range []Backend{SliceBackend, TreeBackend} {
+			set := base(backend)
+			set.Remove(test.remove)
+			if !reflect.DeepEqual(set.set.slice(), test.expected) {
+				t.Errorf("Remove(%s) with backend %d: expected %v, got %v", test.remove, backend, test.expected, set.set.slice())
+			}
+		}
+	}
+}
+
+func TestAddWith(t *testing.T) {
+	type test struct {
+		span     Span
+		value    int
+		policy   OverlapPolicy
+		err      error
+		expected []spanValue[int, int]
+	}
+	base := func(backend Backend) RangeSet[int] {
+		return makeRangeSetWith(backend,
+			spanValue[int, int]{Span{0, 5}, 1},
+			spanValue[int, int]{Span{10, 15}, 2},
+		)
+	}
+	sum := func(x, y *int) int { return *x + *y }
+	for _, test := range []test{
+		{Span{5, 10}, 7, OverlapReject, nil, []spanValue[int, int]{{Span{0, 5}, 1}, {Span{5, 10}, 7}, {Span{10, 15}, 2}}},
+		{Span{4, 10}, 7, OverlapReject, ErrOverlap, []spanValue[int, int]{{Span{0, 5}, 1}, {Span{10, 15}, 2}}},
+		{Span{3, 12}, 7, OverlapOverwrite, nil, []spanValue[int, int]{{Span{0, 3}, 1}, {Span{3, 12}, 7}, {Span{12, 15}, 2}}},
+		{Span{3, 12}, 7, OverlapMerge, nil, []spanValue[int, int]{{Span{0, 3}, 1}, {Span{3, 5}, 8}, {Span{5, 10}, 7}, {Span{10, 12}, 9}, {Span{12, 15}, 2}}},
+		{Span{-2, 20}, 7, OverlapMerge, nil, []spanValue[int, int]{{Span{-2, 0}, 7}, {Span{0, 5}, 8}, {Span{5, 10}, 7}, {Span{10, 15}, 9}, {Span{15, 20}, 7}}},
+		{Span{1, 2}, 7, OverlapMerge, nil, []spanValue[int, int]{{Span{0, 1}, 1}, {Span{1, 2}, 8}, {Span{2, 5}, 1}, {Span{10, 15}, 2}}},
+	} {
+		for _, backend := range []Backend{SliceBackend, TreeBackend} {
+			set := base(backend)
+			err := set.AddWith(test.span, test.value, test.policy, sum)
+			if !errors.Is(err, test.err) {
+				t.Errorf("AddWith(%s, %d) with backend %d: expected error %v, got %v", test.span, test.policy, backend, test.err, err)
+			}
+			if !reflect.DeepEqual(set.set.slice(), test.expected) {
+				t.Errorf("AddWith(%s, %d) with backend %d: expected %v, got %v", test.span, test.policy, backend, test.expected, set.set.slice())
+			}
+		}
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	s := makeRangeSet(
+		spanValue[int, int]{Span{0, 10}, 1},
+		spanValue[int, int]{Span{20, 30}, 2},
+	)
+	u := makeRangeSet(
+		spanValue[int, int]{Span{5, 8}, 3},
+		spanValue[int, int]{Span{9, 22}, 4},
+		spanValue[int, int]{Span{40, 45}, 5},
+	)
+	type test struct {
+		name     string
+		result   RangeSet[int]
+		expected []spanValue[int, int]
+	}
+	for _, test := range []test{
+		{"Difference", s.Difference(u), []spanValue[int, int]{{Span{0, 5}, 1}, {Span{8, 9}, 1}, {Span{22, 30}, 2}}},
+		{"Difference", u.Difference(s), []spanValue[int, int]{{Span{10, 20}, 4}, {Span{40, 45}, 5}}},
+		{"Union", s.Union(u), []spanValue[int, int]{{Span{0, 10}, 1}, {Span{10, 20}, 4}, {Span{20, 30}, 2}, {Span{40, 45}, 5}}},
+		{"SymmetricDifference", s.SymmetricDifference(u), []spanValue[int, int]{
+			{Span{0, 5}, 1}, {Span{8, 9}, 1}, {Span{10, 20}, 4}, {Span{22, 30}, 2}, {Span{40, 45}, 5}}},
+		{"Complement", s.Complement(Span{-5, 25}, 0), []spanValue[int, int]{{Span{-5, 0}, 0}, {Span{10, 20}, 0}}},
+		{"Complement", s.Complement(Span{3, 8}, 0), nil},
+		{"Complement", u.Complement(Span{6, 50}, 0), []spanValue[int, int]{{Span{8, 9}, 0}, {Span{22, 40}, 0}, {Span{45, 50}, 0}}},
+	} {
+		if !reflect.DeepEqual(test.result.set.slice(), test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, test.result.set.slice())
+		}
+	}
+}
+
+func TestCover(t *testing.T) {
+	digits := func(x, y *int) int {
+		return *x*10 + *y
+	}
+	type test struct {
+		s, t     []spanValue[int, int]
+		expected []spanValue[int, int]
+	}
+	for _, test := range []test{
+		{
+			[]spanValue[int, int]{{Span{0, 10}, 1}},
+			[]spanValue[int, int]{{Span{5, 15}, 2}},
+			[]spanValue[int, int]{{Span{0, 5}, 1}, {Span{5, 10}, 12}, {Span{10, 15}, 2}},
+		},
+		{
+			[]spanValue[int, int]{{Span{5, 15}, 1}},
+			[]spanValue[int, int]{{Span{0, 10}, 2}},
+			[]spanValue[int, int]{{Span{0, 5}, 2}, {Span{5, 10}, 12}, {Span{10, 15}, 1}},
+		},
+		{
+			[]spanValue[int, int]{{Span{0, 10}, 1}, {Span{10, 20}, 3}},
+			[]spanValue[int, int]{{Span{5, 10}, 2}, {Span{10, 15}, 4}},
+			[]spanValue[int, int]{{Span{0, 5}, 1}, {Span{5, 10}, 12}, {Span{10, 15}, 34}, {Span{15, 20}, 3}},
+		},
+		{nil, []spanValue[int, int]{{Span{0, 10}, 2}}, []spanValue[int, int]{{Span{0, 10}, 2}}},
+		{[]spanValue[int, int]{{Span{0, 10}, 1}}, nil, []spanValue[int, int]{{Span{0, 10}, 1}}},
+	} {
+		s, u := makeRangeSet(test.s...), makeRangeSet(test.t...)
+		if result := s.Cover(u, digits); !reflect.DeepEqual(result.set.slice(), test.expected) {
+			t.Errorf("%s.Cover(%s): expected %v, got %v", s, u, test.expected, result.set.slice())
+		}
+	}
+}
+
+func TestCombineMap(t *testing.T) {
+	makeMap := func(entries ...spanValue[int, int]) RangeMap {
+		return RangeMap(makeRangeSet(entries...))
+	}
+	type test struct {
+		s, t          RangeMap
+		mergeUnmapped bool
+	}
+	for _, test := range []test{
+		{makeMap(), makeMap(spanValue[int, int]{Span{0, 1}, 2}), false},
+		{makeMap(), makeMap(spanValue[int, int]{Span{0, 1}, 2}), true},
+		{makeMap(spanValue[int, int]{Span{5, 6}, -2}), makeMap(spanValue[int, int]{Span{1, 3}, 5}), true},
+		{makeMap(spanValue[int, int]{Span{4, 7}, -6}), makeMap(spanValue[int, int]{Span{0, 2}, 0}), true},
+		{makeMap(spanValue[int, int]{Span{0, 5}, 10}, spanValue[int, int]{Span{10, 15}, -10}),
+			makeMap(spanValue[int, int]{Span{3, 12}, 1}), false},
+	} {
+		combined := test.s.CombineMap(test.t, test.mergeUnmapped)
+		for point := -5; point < 20; point++ {
+			expected := point
+			if RangeSet[int](test.s).Get(point) != nil || (test.mergeUnmapped && RangeSet[int](test.t).Get(point) != nil) {
+				expected = test.t.Map(test.s.Map(point))
+			}
+			if got := combined.Map(point); got != expected {
+				t.Errorf("%s.CombineMap(%s, %t).Map(%d): expected %d, got %d",
+					test.s, test.t, test.mergeUnmapped, point, expected, got)
+			}
+		}
+	}
+}
+
+func TestInvert(t *testing.T) {
+	type test struct {
+		rangeMap RangeMap
+		err      error
+	}
+	makeMap := func(entries ...spanValue[int, int]) RangeMap {
+		return RangeMap(makeRangeSet(entries...))
+	}
+	for _, test := range []test{
+		{makeMap(), nil},
+		{makeMap(spanValue[int, int]{Span{0, 5}, 10}, spanValue[int, int]{Span{10, 15}, -10}), nil},
+		{makeMap(spanValue[int, int]{Span{0, 5}, 5}, spanValue[int, int]{Span{5, 10}, 5}, spanValue[int, int]{Span{10, 15}, -10}), nil},
+		{makeMap(spanValue[int, int]{Span{0, 5}, 3}), ErrNotInvertible},
+		{makeMap(spanValue[int, int]{Span{0, 5}, 10}, spanValue[int, int]{Span{5, 10}, 6}), ErrNotInvertible},
+	} {
+		inverse, err := test.rangeMap.Invert()
+		if !errors.Is(err, test.err) {
+			t.Errorf("Invert(%s): expected error %v, got %v", test.rangeMap, test.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		for value := -5; value < 25; value++ {
+			if mapped := test.rangeMap.Map(value); inverse.Map(mapped) != value {
+				t.Errorf("Invert(%s): %d maps to %d but inverse maps it to %d",
+					test.rangeMap, value, mapped, inverse.Map(mapped))
+			}
+		}
+	}
+}
+
+func TestMapSpan(t *testing.T) {
+	rangeMap := RangeMap(makeRangeSet(
+		spanValue[int, int]{Span{0, 5}, 10},
+		spanValue[int, int]{Span{10, 15}, -10},
+		spanValue[int, int]{Span{20, 25}, 1},
+	))
+	type test struct {
+		span     Span
+		expected []Span
+	}
+	for _, test := range []test{
+		{Span{-5, -1}, []Span{{-5, -1}}},
+		{Span{3, 3}, nil},
+		{Span{0, 15}, []Span{{0, 15}}},
+		{Span{2, 12}, []Span{{0, 2}, {5, 10}, {12, 15}}},
+		{Span{18, 30}, []Span{{18, 20}, {21, 30}}},
+	} {
+		var spans []Span
+		rangeMap.MapSpan(test.span).Do(func(span Span, _ *struct{}) bool {
+			spans = append(spans, span)
+			return true
+		})
+		if !reflect.DeepEqual(spans, test.expected) {
+			t.Errorf("MapSpan(%s): expected %v, got %v", test.span, test.expected, spans)
+		}
+	}
+}
+
+func TestUnsignedRangeMap(t *testing.T) {
+	var base uint64 = 1 << 63
+	rangeMap := RangeMapOf[uint64]{}
+	if err := rangeMap.AddWith(SpanOf[uint64]{base, base + 10}, -base, OverlapReject, nil); err != nil {
+		t.Fatalf("AddWith: unexpected error %v", err)
+	}
+	rangeMap.Add(SpanOf[uint64]{0, 10}, base)
+	for value := uint64(0); value < 10; value++ {
+		if mapped := rangeMap.Map(base + value); mapped != value {
+			t.Errorf("Map(%d): expected %d, got %d", base+value, value, mapped)
+		}
+		if mapped := rangeMap.Map(value); mapped != base+value {
+			t.Errorf("Map(%d): expected %d, got %d", value, base+value, mapped)
+		}
+	}
+	inverse, err := rangeMap.Invert()
+	if err != nil {
+		t.Fatalf("Invert: unexpected error %v", err)
+	}
+	if mapped := inverse.Map(base + 3); mapped != 3 {
+		t.Errorf("Invert: expected %d, got %d", 3, mapped)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	set := makeRangeSet(
+		spanValue[int, int]{Span{0, 5}, 3},
+		spanValue[int, int]{Span{5, 9}, 3},
+		spanValue[int, int]{Span{9, 12}, 3},
+		spanValue[int, int]{Span{13, 15}, 3},
+		spanValue[int, int]{Span{15, 20}, 4},
+		spanValue[int, int]{Span{20, 22}, 4},
+	)
+	expected := []spanValue[int, int]{{Span{0, 12}, 3}, {Span{13, 15}, 3}, {Span{15, 22}, 4}}
+	if normalized := Coalesce(set); !reflect.DeepEqual(normalized.set.slice(), expected) {
+		t.Errorf("Coalesce: expected %v, got %v", expected, normalized.set.slice())
+	}
+	if count := RangeMap(set).Normalize().Count(); count != len(expected) {
+		t.Errorf("Normalize: expected %d ranges, got %d", len(expected), count)
+	}
+
+	maps := []RangeMap{
+		RangeMap(makeRangeSet(spanValue[int, int]{Span{0, 10}, 5}, spanValue[int, int]{Span{10, 20}, -10})),
+		RangeMap(makeRangeSet(spanValue[int, int]{Span{0, 10}, 10}, spanValue[int, int]{Span{10, 15}, -5})),
+		RangeMap(makeRangeSet(spanValue[int, int]{Span{5, 20}, -5})),
+	}
+	reduced := maps[0].Reduce(maps[1:])
+	normalized := maps[0].ReduceWith(maps[1:], true)
+	for value := -5; value < 30; value++ {
+		if reduced.Map(value) != normalized.Map(value) {
+			t.Errorf("ReduceWith(%d): expected %d, got %d", value, reduced.Map(value), normalized.Map(value))
+		}
+	}
+	if normalized.Count() > reduced.Count() {
+		t.Errorf("ReduceWith: expected at most %d ranges, got %s", reduced.Count(), normalized)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if debugChecks {
+		t.Skip("invalid sets panic with debug checks enabled")
+	}
+	type test struct {
+		entries []spanValue[int, int]
+		valid   bool
+	}
+	for _, test := range []test{
+		{[]spanValue[int, int]{}, true},
+		{[]spanValue[int, int]{{Span{0, 5}, 1}, {Span{5, 10}, 2}}, true},
+		{[]spanValue[int, int]{{Span{0, 5}, 1}, {Span{4, 10}, 2}}, false},
+		{[]spanValue[int, int]{{Span{0, 5}, 1}, {Span{2, 3}, 2}}, false},
+		{[]spanValue[int, int]{{Span{3, 3}, 1}}, false},
+	} {
+		for _, backend := range []Backend{SliceBackend, TreeBackend} {
+			set := makeRangeSetWith(backend, test.entries...)
+			if err := set.Validate(); (err == nil) != test.valid {
+				t.Errorf("Validate(%s) with backend %d: expected valid %t, got %v", set, backend, test.valid, err)
+			}
+		}
+	}
+}
+
+func TestWindowQueries(t *testing.T) {
+	for _, backend := range []Backend{SliceBackend, TreeBackend} {
+		set := makeRangeSetWith(backend,
+			spanValue[int, int]{Span{0, 5}, 1},
+			spanValue[int, int]{Span{5, 10}, 2},
+			spanValue[int, int]{Span{20, 30}, 3},
+		)
+		type test struct {
+			window   Span
+			clip     bool
+			expected []Span
+		}
+		for _, test := range []test{
+			{Span{3, 22}, false, []Span{{0, 5}, {5, 10}, {20, 30}}},
+			{Span{3, 22}, true, []Span{{3, 5}, {5, 10}, {20, 22}}},
+			{Span{10, 20}, false, []Span{}},
+			{Span{4, 4}, false, []Span{}},
+			{Span{-5, 1}, true, []Span{{0, 1}}},
+		} {
+			got := make([]Span, 0)
+			for _, result := range set.Overlapping(test.window, test.clip) {
+				if set.Get(result.Span[0]) != result.Value {
+					t.Errorf("Overlapping(%s): %s has the wrong value", test.window, result.Span)
+				}
+				got = append(got, result.Span)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Overlapping(%s, %t): expected %v, got %v", test.window, test.clip, test.expected, got)
+			}
+		}
+
+		for _, test := range []struct {
+			key            int
+			floor, ceiling *Span
+		}{
+			{-1, nil, &Span{0, 5}},
+			{0, &Span{0, 5}, &Span{0, 5}},
+			{7, &Span{5, 10}, &Span{5, 10}},
+			{10, &Span{5, 10}, &Span{20, 30}},
+			{15, &Span{5, 10}, &Span{20, 30}},
+			{30, &Span{20, 30}, nil},
+		} {
+			for name, result := range map[string]*RangeResult[int]{"Floor": set.Floor(test.key), "Ceiling": set.Ceiling(test.key)} {
+				expected := test.floor
+				if name == "Ceiling" {
+					expected = test.ceiling
+				}
+				if (result == nil) != (expected == nil) || (result != nil && result.Span != *expected) {
+					t.Errorf("%s(%d): expected %v, got %v", name, test.key, expected, result)
+				}
+			}
+		}
+
+		if measure := set.Measure(); measure != 20 {
+			t.Errorf("Measure: expected 20, got %d", measure)
+		}
+	}
+}
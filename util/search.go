@@ -0,0 +1,97 @@
+package util
+
+import (
+	"container/heap"
+)
+
+// searchNode is a span of points part way through a chain of maps.
+type searchNode[K Signed] struct {
+	stage int       // the number of maps applied
+	span  SpanOf[K] // the image of the points after stage maps
+	delta K         // the total shift of the points so far
+	bound K         // a bound on the final images of the points
+}
+
+// searchHeap orders nodes by bound, least first unless greatest is set.
+type searchHeap[K Signed] struct {
+	nodes    []searchNode[K]
+	greatest bool
+}
+
+func (h *searchHeap[K]) Len() int { return len(h.nodes) }
+func (h *searchHeap[K]) Less(i, j int) bool {
+	if h.greatest {
+		return h.nodes[i].bound > h.nodes[j].bound
+	}
+	return h.nodes[i].bound < h.nodes[j].bound
+}
+func (h *searchHeap[K]) Swap(i, j int) { h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i] }
+func (h *searchHeap[K]) Push(x any)    { h.nodes = append(h.nodes, x.(searchNode[K])) }
+func (h *searchHeap[K]) Pop() any {
+	last := h.nodes[len(h.nodes)-1]
+	h.nodes = h.nodes[:len(h.nodes)-1]
+	return last
+}
+
+// searchImage finds the least or greatest image of a domain through maps.
+//
+// Each node's bound is its least (greatest) point plus the most negative
+// (positive) delta of each map still to apply, which no final image can
+// beat. Nodes are expanded best bound first, so the first node through
+// every map holds the answer, and spans whose bound is worse are never
+// split.
+func searchImage[K Signed](domain SpanSetOf[K], maps []RangeMapOf[K], greatest bool) (K, K, bool) {
+	// slack[i] bounds the total delta of maps[i:].
+	slack := make([]K, len(maps)+1)
+	for index := len(maps) - 1; index >= 0; index-- {
+		extreme := K(0)
+		for info := range maps[index].set.from(0) {
+			if greatest {
+				extreme = max(extreme, info.value)
+			} else {
+				extreme = min(extreme, info.value)
+			}
+		}
+		slack[index] = slack[index+1] + extreme
+	}
+	nodes := &searchHeap[K]{greatest: greatest}
+	push := func(stage int, span SpanOf[K], delta K) {
+		point := span[0]
+		if greatest {
+			point = span[1] - 1
+		}
+		heap.Push(nodes, searchNode[K]{stage, span, delta, point + slack[stage]})
+	}
+	for info := range domain.set.from(0) {
+		if !info.span.Empty() {
+			push(0, info.span, 0)
+		}
+	}
+	for nodes.Len() > 0 {
+		node := heap.Pop(nodes).(searchNode[K])
+		if node.stage == len(maps) {
+			return node.bound, node.bound - node.delta, true
+		}
+		maps[node.stage].doSpan(node.span, func(part SpanOf[K], delta K) {
+			push(node.stage+1, part.Shift(delta), node.delta+delta)
+		})
+	}
+	return 0, 0, false
+}
+
+// MinImage returns the least image of the points of a domain through a
+// chain of maps applied in order, and a point of the domain which maps to
+// it. It returns false if the domain is empty.
+//
+// Unlike composing the maps with Reduce, MinImage only splits the spans of
+// the domain which may hold the answer.
+func MinImage[K Signed](domain SpanSetOf[K], maps ...RangeMapOf[K]) (image, point K, ok bool) {
+	return searchImage(domain, maps, false)
+}
+
+// MaxImage returns the greatest image of the points of a domain through a
+// chain of maps applied in order, and a point of the domain which maps to
+// it. It returns false if the domain is empty.
+func MaxImage[K Signed](domain SpanSetOf[K], maps ...RangeMapOf[K]) (image, point K, ok bool) {
+	return searchImage(domain, maps, true)
+}
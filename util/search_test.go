@@ -0,0 +1,69 @@
+package util
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestMinMaxImage(t *testing.T) {
+	pipeline := samplePipeline(t)
+	maps := make([]RangeMap, 0)
+	for _, stage := range pipeline.Stages() {
+		maps = append(maps, stage.Map)
+	}
+	seeds := SpanSet{}
+	seeds.Add(Span{55, 68}, struct{}{})
+	seeds.Add(Span{79, 93}, struct{}{})
+	if image, point, ok := MinImage(seeds, maps...); !ok || image != 46 || point != 82 {
+		t.Errorf("MinImage(%s): expected 46 from 82, got %d from %d, %t", seeds, image, point, ok)
+	}
+	if _, _, ok := MaxImage(SpanSet{}, maps...); ok {
+		t.Errorf("MaxImage({}): expected no image")
+	}
+
+	random := rand.New(rand.NewPCG(9, 10))
+	for trial := 0; trial < 200; trial++ {
+		maps := make([]RangeMap, random.IntN(5))
+		for index := range maps {
+			for range random.IntN(6) {
+				lo := random.IntN(100) - 50
+				maps[index].AddWith(Span{lo, lo + 1 + random.IntN(30)}, random.IntN(81)-40, OverlapOverwrite, nil)
+			}
+		}
+		domain := SpanSet{}
+		for range 1 + random.IntN(3) {
+			lo := random.IntN(100) - 50
+			domain.AddWith(Span{lo, lo + 1 + random.IntN(20)}, struct{}{}, OverlapOverwrite, nil)
+		}
+		least, greatest := 0, 0
+		for index := range domain.set.len() {
+			span := domain.set.at(index).span
+			for point := span[0]; point < span[1]; point++ {
+				image := point
+				for _, rangeMap := range maps {
+					image = rangeMap.Map(image)
+				}
+				if (index == 0 && point == span[0]) || image < least {
+					least = image
+				}
+				if (index == 0 && point == span[0]) || image > greatest {
+					greatest = image
+				}
+			}
+		}
+		for name, search := range map[string]func(SpanSet, ...RangeMap) (int, int, bool){"MinImage": MinImage[int], "MaxImage": MaxImage[int]} {
+			expected := least
+			if name == "MaxImage" {
+				expected = greatest
+			}
+			image, point, ok := search(domain, maps...)
+			mapped := point
+			for _, rangeMap := range maps {
+				mapped = rangeMap.Map(mapped)
+			}
+			if !ok || image != expected || mapped != image || domain.Get(point) == nil {
+				t.Fatalf("%s(%s, %v): expected %d, got %d from %d, %t", name, domain, maps, expected, image, point, ok)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpanOf is the half-open interval [s[0], s[1]) of integers.
+type SpanOf[K Integer] [2]K
+type Span = SpanOf[int]
+
+// SpanLen returns the span [start, start+length).
+func SpanLen[K Integer](start, length K) SpanOf[K] {
+	return SpanOf[K]{start, start + length}
+}
+
+// ParseSpan parses a span written as "[a,b)", as the inclusive range "a-b",
+// or as "start length". Reversed bounds, negative lengths and ends past the
+// largest value of K are errors.
+func ParseSpan[K Integer](text string) (SpanOf[K], error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "[") {
+		var span SpanOf[K]
+		if err := span.UnmarshalText([]byte(text)); err != nil {
+			return SpanOf[K]{}, err
+		}
+		if span[1] < span[0] {
+			return SpanOf[K]{}, fmt.Errorf("invalid span %q: bounds are reversed", text)
+		}
+		return span, nil
+	}
+	if fields := strings.Fields(text); len(fields) == 2 {
+		start, err := parseInteger[K](fields[0])
+		if err != nil {
+			return SpanOf[K]{}, fmt.Errorf("invalid span %q: %w", text, err)
+		}
+		length, err := parseInteger[K](fields[1])
+		if err != nil {
+			return SpanOf[K]{}, fmt.Errorf("invalid span %q: %w", text, err)
+		}
+		if length < 0 {
+			return SpanOf[K]{}, fmt.Errorf("invalid span %q: length is negative", text)
+		}
+		span := SpanLen(start, length)
+		if span[1] < span[0] {
+			return SpanOf[K]{}, fmt.Errorf("invalid span %q: end overflows", text)
+		}
+		return span, nil
+	}
+	// Skip a leading sign, so that "-5--3" splits after "-5".
+	dash := strings.IndexByte(text[min(1, len(text)):], '-') + 1
+	if dash <= 0 {
+		return SpanOf[K]{}, fmt.Errorf("invalid span %q", text)
+	}
+	first, err := parseInteger[K](strings.TrimSpace(text[:dash]))
+	if err != nil {
+		return SpanOf[K]{}, fmt.Errorf("invalid span %q: %w", text, err)
+	}
+	last, err := parseInteger[K](strings.TrimSpace(text[dash+1:]))
+	if err != nil {
+		return SpanOf[K]{}, fmt.Errorf("invalid span %q: %w", text, err)
+	}
+	if last < first {
+		return SpanOf[K]{}, fmt.Errorf("invalid span %q: bounds are reversed", text)
+	}
+	if last+1 < last {
+		return SpanOf[K]{}, fmt.Errorf("invalid span %q: end overflows", text)
+	}
+	return SpanOf[K]{first, last + 1}, nil
+}
+
+// Len returns the number of points in the span.
+func (s SpanOf[K]) Len() K {
+	if s.Empty() {
+		return 0
+	}
+	return s[1] - s[0]
+}
+
+// Empty reports whether the span contains no points.
+func (s SpanOf[K]) Empty() bool {
+	return s[0] >= s[1]
+}
+
+func (s SpanOf[K]) Contains(value K) bool {
+	return s[0] <= value && value < s[1]
+}
+
+func (s SpanOf[K]) Overlaps(t SpanOf[K]) bool {
+	return !(t[1] <= s[0] || t[0] >= s[1])
+}
+
+func (s SpanOf[K]) Intersect(t SpanOf[K]) SpanOf[K] {
+	result := s
+	if t[0] > s[0] {
+		result[0] = t[0]
+	}
+	if t[1] < s[1] {
+		result[1] = t[1]
+	}
+	if result[1] < result[0] {
+		result[0] = result[1]
+	}
+	return result
+}
+
+// Union returns the smallest span covering both spans, if they overlap or
+// touch so that it covers no other points.
+func (s SpanOf[K]) Union(t SpanOf[K]) (SpanOf[K], bool) {
+	switch {
+	case t.Empty():
+		return s, true
+	case s.Empty():
+		return t, true
+	case t[0] > s[1] || s[0] > t[1]:
+		return SpanOf[K]{}, false
+	}
+	return SpanOf[K]{min(s[0], t[0]), max(s[1], t[1])}, true
+}
+
+// Subtract returns the non-empty parts of s which are not in t, in order.
+func (s SpanOf[K]) Subtract(t SpanOf[K]) []SpanOf[K] {
+	if s.Empty() {
+		return nil
+	}
+	if t.Empty() || !s.Overlaps(t) {
+		return []SpanOf[K]{s}
+	}
+	pieces := make([]SpanOf[K], 0, 2)
+	if s[0] < t[0] {
+		pieces = append(pieces, SpanOf[K]{s[0], t[0]})
+	}
+	if t[1] < s[1] {
+		pieces = append(pieces, SpanOf[K]{t[1], s[1]})
+	}
+	return pieces
+}
+
+// Shift returns the span moved by delta.
+func (s SpanOf[K]) Shift(delta K) SpanOf[K] {
+	return SpanOf[K]{s[0] + delta, s[1] + delta}
+}
+
+// Clamp returns the point of a non-empty span nearest to value.
+func (s SpanOf[K]) Clamp(value K) K {
+	return max(s[0], min(value, s[1]-1))
+}
+
+// Split splits the span in two at a point, either of which may be empty.
+func (s SpanOf[K]) Split(at K) (SpanOf[K], SpanOf[K]) {
+	at = max(s[0], min(at, s[1]))
+	return SpanOf[K]{s[0], at}, SpanOf[K]{at, s[1]}
+}
+
+func (s SpanOf[K]) String() string {
+	return fmt.Sprintf("[%d,%d)", s[0], s[1])
+}
@@ -0,0 +1,99 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpanArithmetic(t *testing.T) {
+	type test struct {
+		s, t     Span
+		union    Span
+		unionOk  bool
+		subtract []Span
+	}
+	for _, test := range []test{
+		{Span{0, 10}, Span{3, 5}, Span{0, 10}, true, []Span{{0, 3}, {5, 10}}},
+		{Span{0, 10}, Span{10, 15}, Span{0, 15}, true, []Span{{0, 10}}},
+		{Span{0, 10}, Span{-5, 4}, Span{-5, 10}, true, []Span{{4, 10}}},
+		{Span{0, 10}, Span{-5, 20}, Span{-5, 20}, true, []Span{}},
+		{Span{0, 10}, Span{12, 15}, Span{}, false, []Span{{0, 10}}},
+		{Span{0, 10}, Span{4, 4}, Span{0, 10}, true, []Span{{0, 10}}},
+		{Span{5, 5}, Span{1, 2}, Span{1, 2}, true, []Span{}},
+	} {
+		if union, ok := test.s.Union(test.t); union != test.union || ok != test.unionOk {
+			t.Errorf("%s.Union(%s): expected %s, %t, got %s, %t", test.s, test.t, test.union, test.unionOk, union, ok)
+		}
+		if pieces := append([]Span{}, test.s.Subtract(test.t)...); !reflect.DeepEqual(pieces, test.subtract) {
+			t.Errorf("%s.Subtract(%s): expected %v, got %v", test.s, test.t, test.subtract, pieces)
+		}
+	}
+
+	span := Span{3, 8}
+	if span.Len() != 5 || span.Empty() || (Span{8, 3}).Len() != 0 || !(Span{8, 3}).Empty() {
+		t.Errorf("Len, Empty: wrong for %s or [8,3)", span)
+	}
+	if shifted := span.Shift(-10); shifted != (Span{-7, -2}) {
+		t.Errorf("%s.Shift(-10): expected [-7,-2), got %s", span, shifted)
+	}
+	for value, expected := range map[int]int{0: 3, 5: 5, 8: 7, 100: 7} {
+		if clamped := span.Clamp(value); clamped != expected {
+			t.Errorf("%s.Clamp(%d): expected %d, got %d", span, value, expected, clamped)
+		}
+	}
+	for at, expected := range map[int][2]Span{1: {{3, 3}, {3, 8}}, 5: {{3, 5}, {5, 8}}, 9: {{3, 8}, {8, 8}}} {
+		if before, after := span.Split(at); before != expected[0] || after != expected[1] {
+			t.Errorf("%s.Split(%d): expected %v, got %s, %s", span, at, expected, before, after)
+		}
+	}
+}
+
+func TestParseSpan(t *testing.T) {
+	type test struct {
+		text  string
+		span  Span
+		valid bool
+	}
+	for _, test := range []test{
+		{"[0,5)", Span{0, 5}, true},
+		{"3-7", Span{3, 8}, true},
+		{"-5--3", Span{-5, -2}, true},
+		{"-5 - 3", Span{-5, 4}, true},
+		{"79 14", Span{79, 93}, true},
+		{" -4 2 ", Span{-4, -2}, true},
+		{"[0,5]", Span{}, false},
+		{"7", Span{}, false},
+		{"-7", Span{}, false},
+		{"", Span{}, false},
+		{"1 2 3", Span{}, false},
+		{"a-b", Span{}, false},
+		{"[5,5)", Span{5, 5}, true},
+		{"[7,3)", Span{}, false},
+		{"7-3", Span{}, false},
+		{"5 0", Span{5, 5}, true},
+		{"5 -3", Span{}, false},
+	} {
+		span, err := ParseSpan[int](test.text)
+		if (err == nil) != test.valid || (test.valid && span != test.span) {
+			t.Errorf("ParseSpan(%q): expected %s (valid %t), got %s, %v", test.text, test.span, test.valid, span, err)
+		}
+	}
+	for _, test := range []struct {
+		text  string
+		valid bool
+	}{
+		{"250 3", true},
+		{"250 5", true},
+		{"250 10", false},
+		{"0-254", true},
+		{"0-255", false},
+		{"[0,255)", true},
+	} {
+		if span, err := ParseSpan[uint8](test.text); (err == nil) != test.valid {
+			t.Errorf("ParseSpan[uint8](%q): expected valid %t, got %s, %v", test.text, test.valid, span, err)
+		}
+	}
+	if _, err := ParseSpan[int8]("120 10"); err == nil {
+		t.Errorf("ParseSpan[int8](\"120 10\"): expected an overflow error")
+	}
+}
@@ -0,0 +1,279 @@
+package util
+
+import (
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"sort"
+)
+
+// Backend selects the storage used by a RangeSet.
+type Backend int
+
+const (
+	// SliceBackend stores ranges in a sorted slice. Lookups and appends in
+	// order are cheap, but inserting out of order shifts the tail: building a
+	// set from n unsorted spans costs O(n^2).
+	SliceBackend Backend = iota
+	// TreeBackend stores ranges in a treap ordered by span, so inserts and
+	// removals cost O(log n) wherever they fall. Indexed access also costs
+	// O(log n), which slows the sweeps in Cover, Intersect and friends.
+	TreeBackend
+)
+
+// spanStore holds the sorted ranges of a set.
+//
+// The zero value is an empty slice-backed store.
+type spanStore[K Integer, T any] struct {
+	items []spanValue[K, T]
+	tree  *spanTree[K, T]
+}
+
+func newSpanStore[K Integer, T any](backend Backend) spanStore[K, T] {
+	if backend == TreeBackend {
+		return spanStore[K, T]{tree: &spanTree[K, T]{}}
+	}
+	return spanStore[K, T]{}
+}
+
+func (s *spanStore[K, T]) backend() Backend {
+	if s.tree != nil {
+		return TreeBackend
+	}
+	return SliceBackend
+}
+
+func (s *spanStore[K, T]) len() int {
+	if s.tree != nil {
+		return s.tree.root.count()
+	}
+	return len(s.items)
+}
+
+func (s *spanStore[K, T]) at(index int) *spanValue[K, T] {
+	if s.tree != nil {
+		return s.tree.root.at(index)
+	}
+	return &s.items[index]
+}
+
+// bisect returns the index of the first range which ends after key.
+//
+// This is the range containing key if there is one, otherwise the first
+// range after key.
+func (s *spanStore[K, T]) bisect(key K) int {
+	if s.tree != nil {
+		return s.tree.root.bisect(key)
+	}
+	return sort.Search(len(s.items), func(index int) bool {
+		return s.items[index].span.Contains(key) || s.items[index].span[0] > key
+	})
+}
+
+// replace replaces the ranges in [from,to) with items.
+func (s *spanStore[K, T]) replace(from, to int, items ...spanValue[K, T]) {
+	if s.tree != nil {
+		s.tree.replace(from, to, items)
+		return
+	}
+	s.items = slices.Replace(s.items, from, to, items...)
+}
+
+// from returns an iterator over the ranges in order starting from an index.
+func (s *spanStore[K, T]) from(index int) iter.Seq[*spanValue[K, T]] {
+	return func(yield func(*spanValue[K, T]) bool) {
+		if s.tree != nil {
+			s.tree.root.each(index, yield)
+			return
+		}
+		for next := index; next < len(s.items); next++ {
+			if !yield(&s.items[next]) {
+				return
+			}
+		}
+	}
+}
+
+// backward returns an iterator over the ranges in reverse order, starting
+// from the range before an index.
+func (s *spanStore[K, T]) backward(index int) iter.Seq[*spanValue[K, T]] {
+	return func(yield func(*spanValue[K, T]) bool) {
+		if s.tree != nil {
+			s.tree.root.eachBackward(index, yield)
+			return
+		}
+		for next := min(index, len(s.items)) - 1; next >= 0; next-- {
+			if !yield(&s.items[next]) {
+				return
+			}
+		}
+	}
+}
+
+// slice returns the ranges in order.
+//
+// For the slice backend this shares storage with the set.
+func (s *spanStore[K, T]) slice() []spanValue[K, T] {
+	if s.tree == nil {
+		return s.items
+	}
+	result := make([]spanValue[K, T], 0, s.len())
+	for info := range s.from(0) {
+		result = append(result, *info)
+	}
+	return result
+}
+
+// clone returns a copy of the store with the same backend.
+func (s *spanStore[K, T]) clone() spanStore[K, T] {
+	if s.tree == nil {
+		return spanStore[K, T]{items: slices.Clone(s.items)}
+	}
+	clone := newSpanStore[K, T](TreeBackend)
+	clone.replace(0, 0, s.slice()...)
+	return clone
+}
+
+// spanTree is a treap of ranges keyed implicitly by their position.
+//
+// A persistent tree never changes its nodes: updates copy the nodes on the
+// paths they touch, so other trees may share the rest.
+type spanTree[K Integer, T any] struct {
+	root       *treeNode[K, T]
+	persistent bool
+}
+
+type treeNode[K Integer, T any] struct {
+	item        spanValue[K, T]
+	priority    uint32
+	size        int
+	left, right *treeNode[K, T]
+}
+
+func (n *treeNode[K, T]) count() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *treeNode[K, T]) update() *treeNode[K, T] {
+	n.size = n.left.count() + 1 + n.right.count()
+	return n
+}
+
+func (n *treeNode[K, T]) at(index int) *spanValue[K, T] {
+	for n != nil {
+		left := n.left.count()
+		switch {
+		case index < left:
+			n = n.left
+		case index == left:
+			return &n.item
+		default:
+			index -= left + 1
+			n = n.right
+		}
+	}
+	panic("index out of range")
+}
+
+func (n *treeNode[K, T]) bisect(key K) int {
+	index := 0
+	for n != nil {
+		if n.item.span[1] > key {
+			n = n.left
+		} else {
+			index += n.left.count() + 1
+			n = n.right
+		}
+	}
+	return index
+}
+
+func (n *treeNode[K, T]) each(from int, do func(*spanValue[K, T]) bool) bool {
+	if n == nil {
+		return true
+	}
+	left := n.left.count()
+	if from < left && !n.left.each(from, do) {
+		return false
+	}
+	if from <= left && !do(&n.item) {
+		return false
+	}
+	return n.right.each(max(from-left-1, 0), do)
+}
+
+func (n *treeNode[K, T]) eachBackward(before int, do func(*spanValue[K, T]) bool) bool {
+	if n == nil {
+		return true
+	}
+	left := n.left.count()
+	if before > left+1 && !n.right.eachBackward(before-left-1, do) {
+		return false
+	}
+	if before > left && !do(&n.item) {
+		return false
+	}
+	return n.left.eachBackward(min(before, left), do)
+}
+
+// splitTree splits a treap into its first count nodes and the rest.
+//
+// If persistent is true, nodes are copied rather than changed.
+func splitTree[K Integer, T any](n *treeNode[K, T], count int, persistent bool) (*treeNode[K, T], *treeNode[K, T]) {
+	if n == nil {
+		return nil, nil
+	}
+	if persistent {
+		n = n.copy()
+	}
+	if left := n.left.count(); count <= left {
+		l, r := splitTree(n.left, count, persistent)
+		n.left = r
+		return l, n.update()
+	} else {
+		l, r := splitTree(n.right, count-left-1, persistent)
+		n.right = l
+		return n.update(), r
+	}
+}
+
+// joinTree concatenates two treaps.
+//
+// If persistent is true, nodes are copied rather than changed.
+func joinTree[K Integer, T any](l, r *treeNode[K, T], persistent bool) *treeNode[K, T] {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	case l.priority > r.priority:
+		if persistent {
+			l = l.copy()
+		}
+		l.right = joinTree(l.right, r, persistent)
+		return l.update()
+	default:
+		if persistent {
+			r = r.copy()
+		}
+		r.left = joinTree(l, r.left, persistent)
+		return r.update()
+	}
+}
+
+func (n *treeNode[K, T]) copy() *treeNode[K, T] {
+	c := *n
+	return &c
+}
+
+func (t *spanTree[K, T]) replace(from, to int, items []spanValue[K, T]) {
+	head, tail := splitTree(t.root, to, t.persistent)
+	head, _ = splitTree(head, from, t.persistent)
+	for _, item := range items {
+		head = joinTree(head, &treeNode[K, T]{item: item, priority: rand.Uint32(), size: 1}, t.persistent)
+	}
+	t.root = joinTree(head, tail, t.persistent)
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+// randomSpans returns count disjoint unit-gapped spans in random order.
+func randomSpans(count int, seed uint64) []Span {
+	spans := make([]Span, count)
+	for index := range spans {
+		spans[index] = Span{3 * index, 3*index + 2}
+	}
+	random := rand.New(rand.NewPCG(seed, seed))
+	random.Shuffle(count, func(i, j int) {
+		spans[i], spans[j] = spans[j], spans[i]
+	})
+	return spans
+}
+
+func TestTreeBackend(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2))
+	slice := NewRangeSet[int, int](SliceBackend)
+	tree := NewRangeSet[int, int](TreeBackend)
+	for step := 0; step < 2000; step++ {
+		lo := random.IntN(500)
+		span := Span{lo, lo + 1 + random.IntN(20)}
+		switch random.IntN(3) {
+		case 0:
+			slice.Remove(span)
+			tree.Remove(span)
+		default:
+			policy := OverlapPolicy(random.IntN(3))
+			sum := func(x, y *int) int { return *x + *y }
+			sliceErr := slice.AddWith(span, step, policy, sum)
+			treeErr := tree.AddWith(span, step, policy, sum)
+			if (sliceErr == nil) != (treeErr == nil) {
+				t.Fatalf("step %d: AddWith(%s, %d) errors differ: %v != %v", step, span, policy, sliceErr, treeErr)
+			}
+		}
+		if !reflect.DeepEqual(slice.set.slice(), tree.set.slice()) {
+			t.Fatalf("step %d: backends differ:\n%s\n%s", step, slice, tree)
+		}
+	}
+	for key := -5; key < 550; key++ {
+		if sliceValue, treeValue := slice.Get(key), tree.Get(key); (sliceValue == nil) != (treeValue == nil) ||
+			(sliceValue != nil && *sliceValue != *treeValue) {
+			t.Errorf("Get(%d): backends differ", key)
+		}
+	}
+	other := makeRangeSet(spanValue[int, int]{Span{10, 200}, 1}, spanValue[int, int]{Span{300, 310}, 2})
+	first := func(x, y *int) int { return *x }
+	if expected, got := slice.Cover(other, first), tree.Cover(other, first); !reflect.DeepEqual(expected.set.slice(), got.set.slice()) {
+		t.Errorf("Cover: backends differ:\n%s\n%s", expected, got)
+	}
+	if expected, got := slice.Intersect(other, first), tree.Intersect(other, first); !reflect.DeepEqual(expected.set.slice(), got.set.slice()) {
+		t.Errorf("Intersect: backends differ:\n%s\n%s", expected, got)
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	for _, count := range []int{16, 256, 4096, 16384} {
+		spans := randomSpans(count, uint64(count))
+		for _, backend := range []Backend{SliceBackend, TreeBackend} {
+			name := map[Backend]string{SliceBackend: "slice", TreeBackend: "tree"}[backend]
+			b.Run(fmt.Sprintf("%s/%d", name, count), func(b *testing.B) {
+				for range b.N {
+					set := NewRangeSet[int, int](backend)
+					for index, span := range spans {
+						set.Add(span, index)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	for _, count := range []int{16, 256, 4096, 16384} {
+		spans := randomSpans(count, uint64(count))
+		for _, backend := range []Backend{SliceBackend, TreeBackend} {
+			name := map[Backend]string{SliceBackend: "slice", TreeBackend: "tree"}[backend]
+			set := NewRangeSet[int, int](backend)
+			for index, span := range spans {
+				set.Add(span, index)
+			}
+			b.Run(fmt.Sprintf("%s/%d", name, count), func(b *testing.B) {
+				for index := range b.N {
+					set.Get(index % (3 * count))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package util
+
+// sweepCursor is a set's position in a sweep.
+type sweepCursor[K Integer, T any] struct {
+	store  spanStore[K, T]
+	index  int              // the range at the cursor
+	end    int              // the number of ranges in the set
+	info   *spanValue[K, T] // the range at index, if index is before end
+	active bool             // whether info covers the sweep line
+}
+
+// newCursor returns a cursor on a set at a point.
+func newCursor[K Integer, T any](set RangeSetOf[K, T], from K) sweepCursor[K, T] {
+	cursor := sweepCursor[K, T]{store: set.set, index: set.set.bisect(from), end: set.set.len()}
+	if cursor.index < cursor.end {
+		cursor.info = cursor.store.at(cursor.index)
+		cursor.active = cursor.info.span[0] <= from
+	}
+	return cursor
+}
+
+// key returns the point at which the cursor next changes: the end of the
+// active range, or the start of the next one.
+func (c *sweepCursor[K, T]) key() K {
+	if c.active {
+		return c.info.span[1]
+	}
+	return c.info.span[0]
+}
+
+// current returns the range covering the sweep line, or nil.
+func (c *sweepCursor[K, T]) current() *spanValue[K, T] {
+	if c.active {
+		return c.info
+	}
+	return nil
+}
+
+// boundary is the next point at which a cursor changes.
+type boundary[K Integer] struct {
+	key    K
+	cursor int
+}
+
+// siftDown restores the order of a heap of boundaries below an index. With
+// two boundaries, as in every two-set sweep, it is a single comparison.
+func siftDown[K Integer](queue []boundary[K], index int) {
+	for {
+		child := 2*index + 1
+		if child >= len(queue) {
+			return
+		}
+		if child+1 < len(queue) && queue[child+1].key < queue[child].key {
+			child++
+		}
+		if queue[index].key <= queue[child].key {
+			return
+		}
+		queue[index], queue[child] = queue[child], queue[index]
+		index = child
+	}
+}
+
+// sweep is the stepping routine behind every sweep. It moves a sweep line
+// from a point over cursors which start there, stopping at the boundaries
+// of their ranges, which it keeps in a heap. It visits each segment between
+// stops over which any cursor is active. If moved is not nil, it is called
+// with the index of each cursor which moves.
+func sweep[K Integer, T any](cursors []sweepCursor[K, T], from K, moved func(int), visit func(SpanOf[K]) bool) {
+	queue := make([]boundary[K], 0, len(cursors))
+	count := 0
+	for index := range cursors {
+		if cursor := &cursors[index]; cursor.index < cursor.end {
+			queue = append(queue, boundary[K]{cursor.key(), index})
+			if cursor.active {
+				count++
+			}
+		}
+	}
+	for index := len(queue)/2 - 1; index >= 0; index-- {
+		siftDown(queue, index)
+	}
+	for line := from; len(queue) > 0; {
+		end := queue[0].key
+		if count > 0 && line < end && !visit(SpanOf[K]{line, end}) {
+			return
+		}
+		line = end
+		for len(queue) > 0 && queue[0].key == line {
+			top := queue[0].cursor
+			cursor := &cursors[top]
+			// The cursor's range ends at the line, or the next one starts
+			// there. Ranges do not overlap, so it moves at most one range.
+			if !cursor.active {
+				cursor.active = true
+				count++
+			} else if cursor.index++; cursor.index < cursor.end {
+				cursor.info = cursor.store.at(cursor.index)
+				if cursor.active = cursor.info.span[0] == line; !cursor.active {
+					count--
+				}
+			} else {
+				cursor.active = false
+				count--
+			}
+			if moved != nil {
+				moved(top)
+			}
+			if cursor.index < cursor.end {
+				queue[0].key = cursor.key()
+			} else {
+				queue[0] = queue[len(queue)-1]
+				queue = queue[:len(queue)-1]
+			}
+			siftDown(queue, 0)
+		}
+	}
+}
+
+// Sweep runs a sweep line over several sets at once. It visits, in order,
+// each elementary segment covered by at least one set: a maximal span over
+// which the same ranges are active. The active slice holds, for each set,
+// the range covering the segment, or a result with a nil Value if none does.
+//
+// The sets' cursors are ordered with a heap, so a sweep costs O(n log k)
+// for n ranges in k sets, plus the visits. The active slice is reused
+// between visits. If a visit returns false, no more segments are visited.
+func Sweep[K Integer, T any](sets []RangeSetOf[K, T], visit func(SpanOf[K], []RangeResultOf[K, T]) bool) {
+	var from K
+	found := false
+	for index := range sets {
+		if sets[index].set.len() > 0 && (!found || sets[index].Min() < from) {
+			from, found = sets[index].Min(), true
+		}
+	}
+	cursors := make([]sweepCursor[K, T], len(sets))
+	for index := range sets {
+		cursors[index] = newCursor(sets[index], from)
+	}
+	active := make([]RangeResultOf[K, T], len(sets))
+	moved := func(index int) {
+		active[index] = cursors[index].current().result()
+	}
+	for index := range cursors {
+		moved(index)
+	}
+	sweep(cursors, from, moved, func(span SpanOf[K]) bool {
+		return visit(span, active)
+	})
+}
+
+// result returns the range as a result, or an empty result if it is nil.
+func (s *spanValue[K, T]) result() RangeResultOf[K, T] {
+	if s == nil {
+		return RangeResultOf[K, T]{}
+	}
+	return RangeResultOf[K, T]{s.span, &s.value}
+}
+
+// sweepStart returns the least start of a range in either set.
+func sweepStart[K Integer, T any](s, t RangeSetOf[K, T]) K {
+	switch {
+	case s.set.len() == 0:
+		return t.Min()
+	case t.set.len() == 0:
+		return s.Min()
+	}
+	return min(s.Min(), t.Min())
+}
+
+// sweepPair is Sweep over two sets from a point, clipping any range which
+// begins before it. It passes visit the range of each set covering the
+// segment, or nil, rather than filling a slice, and its heap of two
+// boundaries needs a single comparison per step.
+func sweepPair[K Integer, T any](s, t RangeSetOf[K, T], from K, visit func(span SpanOf[K], sInfo, tInfo *spanValue[K, T]) bool) {
+	cursors := []sweepCursor[K, T]{newCursor(s, from), newCursor(t, from)}
+	sweep(cursors, from, nil, func(span SpanOf[K]) bool {
+		return visit(span, cursors[0].current(), cursors[1].current())
+	})
+}
+
+// sweepPick sweeps two sets and visits, on each segment, the range of the
+// set chosen by pick: 0 for s, 1 for t or -1 for neither. Consecutive
+// segments picked from the same range are visited as one.
+func sweepPick[K Integer, T any](s, t RangeSetOf[K, T], pick func(sInfo, tInfo *spanValue[K, T]) int, visit func(SpanOf[K], *T) bool) {
+	var pending SpanOf[K]
+	var pendingInfo *spanValue[K, T] // the range picked for pending, or nil
+	stopped := false
+	sweepPair(s, t, sweepStart(s, t), func(span SpanOf[K], sInfo, tInfo *spanValue[K, T]) bool {
+		var info *spanValue[K, T]
+		switch pick(sInfo, tInfo) {
+		case 0:
+			info = sInfo
+		case 1:
+			info = tInfo
+		default:
+			return true
+		}
+		if info == pendingInfo && pending[1] == span[0] {
+			pending[1] = span[1]
+			return true
+		}
+		if pendingInfo != nil && !visit(pending, &pendingInfo.value) {
+			stopped = true
+			return false
+		}
+		pending, pendingInfo = span, info
+		return true
+	})
+	if !stopped && pendingInfo != nil {
+		visit(pending, &pendingInfo.value)
+	}
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestSweep(t *testing.T) {
+	sets := make([]RangeSet[string], 3)
+	sets[0].Add(Span{0, 10}, "a")
+	sets[0].Add(Span{10, 12}, "b")
+	sets[1].Add(Span{5, 15}, "c")
+	sets[2] = NewRangeSet[int, string](TreeBackend)
+	sets[2].Add(Span{20, 25}, "d")
+
+	type segment struct {
+		span   Span
+		active string
+	}
+	expected := []segment{
+		{Span{0, 5}, "[0,10)a - -"},
+		{Span{5, 10}, "[0,10)a [5,15)c -"},
+		{Span{10, 12}, "[10,12)b [5,15)c -"},
+		{Span{12, 15}, "- [5,15)c -"},
+		{Span{20, 25}, "- - [20,25)d"},
+	}
+	got := make([]segment, 0)
+	Sweep(sets, func(span Span, active []RangeResult[string]) bool {
+		description := ""
+		for index, result := range active {
+			if index > 0 {
+				description += " "
+			}
+			if result.Value == nil {
+				description += "-"
+			} else {
+				description += result.Span.String() + *result.Value
+			}
+		}
+		got = append(got, segment{span, description})
+		return len(got) < len(expected)
+	})
+	if len(got) != len(expected) {
+		t.Fatalf("Sweep: expected %d segments, got %v", len(expected), got)
+	}
+	for index := range expected {
+		if got[index] != expected[index] {
+			t.Errorf("Sweep: segment %d: expected %v, got %v", index, expected[index], got[index])
+		}
+	}
+
+	count := 0
+	Sweep(sets, func(Span, []RangeResult[string]) bool {
+		count++
+		return count < 2
+	})
+	if count != 2 {
+		t.Errorf("Sweep: expected to stop after 2 segments, got %d", count)
+	}
+}
+
+func TestSweepPair(t *testing.T) {
+	// A third, empty set sends the sweep through the heap, which the pair
+	// must agree with.
+	random := rand.New(rand.NewPCG(5, 6))
+	for range 100 {
+		sets := make([]RangeSet[int], 3)
+		for index := range sets[:2] {
+			for _, span := range randomSpans(1+random.IntN(20), random.Uint64()) {
+				span = Span{span[0] + random.IntN(3), span[1] + random.IntN(2)}
+				sets[index].AddWith(span, index, OverlapOverwrite, nil)
+			}
+		}
+		describe := func(sets []RangeSet[int]) []string {
+			segments := make([]string, 0)
+			Sweep(sets, func(span Span, active []RangeResult[int]) bool {
+				segments = append(segments, fmt.Sprint(span, active[0].Span, active[0].Value != nil, active[1].Span, active[1].Value != nil))
+				return true
+			})
+			return segments
+		}
+		if pair, heap := describe(sets[:2]), describe(sets); !slices.Equal(pair, heap) {
+			t.Errorf("Sweep(%s, %s): pair and heap differ:\n%v\n%v", sets[0], sets[1], pair, heap)
+		}
+	}
+}
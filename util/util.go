@@ -77,6 +77,18 @@ func ParseNumberSet(numberFields string) Set[int] {
 	return result
 }
 
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+type Integer interface {
+	Signed | Unsigned
+}
+
 func Min[T cmp.Ordered](x, y T) T {
 	if x < y {
 		return x
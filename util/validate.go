@@ -0,0 +1,74 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalid = errors.New("invalid range set")
+
+// validate checks that the ranges are non-empty, sorted and disjoint.
+func (s *spanStore[K, T]) validate() error {
+	if s.tree != nil {
+		if err := s.tree.root.validate(); err != nil {
+			return err
+		}
+	}
+	var last *spanValue[K, T]
+	index := 0
+	for info := range s.from(0) {
+		if info.span.Empty() {
+			return fmt.Errorf("%w: range %d %s is empty", ErrInvalid, index, info.span)
+		}
+		if last != nil && last.span[1] > info.span[0] {
+			return fmt.Errorf("%w: range %d %s overlaps or precedes %s", ErrInvalid, index, info.span, last.span)
+		}
+		last = info
+		index++
+	}
+	return nil
+}
+
+func (n *treeNode[K, T]) validate() error {
+	if n == nil {
+		return nil
+	}
+	if n.size != n.left.count()+1+n.right.count() {
+		return fmt.Errorf("%w: tree node %s has size %d", ErrInvalid, n.item.span, n.size)
+	}
+	for _, child := range []*treeNode[K, T]{n.left, n.right} {
+		if child != nil && child.priority > n.priority {
+			return fmt.Errorf("%w: tree node %s is out of heap order", ErrInvalid, child.item.span)
+		}
+	}
+	if err := n.left.validate(); err != nil {
+		return err
+	}
+	return n.right.validate()
+}
+
+// check panics if debug checks are enabled and the ranges are invalid.
+func (s *spanStore[K, T]) check() {
+	if debugChecks {
+		if err := s.validate(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Validate checks that the ranges in the set are non-empty, sorted and disjoint.
+//
+// Build with -tags rangesetdebug to validate after every mutation.
+func (s RangeSetOf[K, T]) Validate() error {
+	return s.set.validate()
+}
+
+// Validate checks that the ranges in the map are non-empty, sorted and disjoint.
+func (s RangeMapOf[K]) Validate() error {
+	return s.set.validate()
+}
+
+// Validate checks that the ranges in the map are non-empty, sorted and disjoint.
+func (s AffineRangeMapOf[K]) Validate() error {
+	return s.set.validate()
+}